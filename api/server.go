@@ -2,22 +2,30 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/alexferl/golib/http/router"
 	"github.com/alexferl/golib/http/server"
 	"github.com/labstack/echo/v4"
+	"github.com/nlopes/slack"
 	"github.com/spf13/viper"
 
 	"github.com/alexferl/uberwachen/handlers"
+	"github.com/alexferl/uberwachen/registries"
 	"github.com/alexferl/uberwachen/storage"
 )
 
 type (
 	Handler struct {
-		Storage storage.Storage
+		Storage    storage.Storage
+		Checks     *registries.Checks
+		Handlers   *registries.Handlers
+		Dispatcher *handlers.Dispatcher
 	}
 )
 
@@ -31,18 +39,127 @@ func (h *Handler) root(c echo.Context) error {
 }
 
 func (h *Handler) GetIncidents(c echo.Context) error {
+	selector, err := handlers.ParseLabelSelector(c.QueryParam("labels"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{err.Error()})
+	}
+
 	incidents := &[]handlers.Incident{}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	err := h.Storage.GetAll(ctx, incidents)
+	err = h.Storage.GetAll(ctx, incidents)
 	if err != nil {
 		m := fmt.Sprintf("Error getting incidents: %v", err)
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: m})
 	}
 
-	return c.JSON(http.StatusOK, map[string]*[]handlers.Incident{"incidents": incidents})
+	filtered := []*handlers.Incident{}
+	for i, incident := range *incidents {
+		if incident.Labels.Matches(selector) {
+			filtered = append(filtered, &(*incidents)[i])
+		}
+	}
+
+	err = h.addDeliveries(ctx, filtered...)
+	if err != nil {
+		m := fmt.Sprintf("Error getting deliveries: %v", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: m})
+	}
+
+	return c.JSON(http.StatusOK, map[string][]*handlers.Incident{"incidents": filtered})
+}
+
+func (h *Handler) GetIncident(c echo.Context) error {
+	id := c.Param("id")
+	incident := &handlers.Incident{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := h.Storage.GetByID(ctx, id, incident)
+	if err != nil {
+		m := fmt.Sprintf("Error getting incident: %v", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: m})
+	}
+
+	if incident.ID == "" {
+		e := fmt.Sprintf("Error incident '%s' not found", id)
+		return c.JSON(http.StatusNotFound, ErrorResponse{e})
+	}
+
+	err = h.addDeliveries(ctx, incident)
+	if err != nil {
+		m := fmt.Sprintf("Error getting deliveries: %v", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: m})
+	}
+
+	return c.JSON(http.StatusOK, incident)
+}
+
+// GetIncidentDeliveries returns the delivery log of an incident, including resolved incidents
+func (h *Handler) GetIncidentDeliveries(c echo.Context) error {
+	id := c.Param("id")
+	deliveries := []*handlers.Delivery{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := h.Storage.GetDeliveries(ctx, []string{id}, &deliveries)
+	if err != nil {
+		m := fmt.Sprintf("Error getting deliveries: %v", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: m})
+	}
+
+	return c.JSON(http.StatusOK, map[string][]*handlers.Delivery{"deliveries": deliveries})
+}
+
+// addDeliveries sets the delivery log of the incidents
+func (h *Handler) addDeliveries(ctx context.Context, incidents ...*handlers.Incident) error {
+	ids := make([]string, 0, len(incidents))
+	for _, incident := range incidents {
+		incident.Deliveries = []*handlers.Delivery{}
+		ids = append(ids, incident.ID)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	deliveries := &[]*handlers.Delivery{}
+	err := h.Storage.GetDeliveries(ctx, ids, deliveries)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*handlers.Incident, len(incidents))
+	for _, incident := range incidents {
+		byID[incident.ID] = incident
+	}
+	for _, d := range *deliveries {
+		if incident, ok := byID[d.IncidentID]; ok {
+			incident.Deliveries = append(incident.Deliveries, d)
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) GetChecks(c echo.Context) error {
+	selector, err := handlers.ParseLabelSelector(c.QueryParam("labels"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{err.Error()})
+	}
+
+	checks := []*handlers.Check{}
+	for _, check := range h.Checks.All() {
+		if check.Labels.Matches(selector) {
+			checks = append(checks, check.Snapshot())
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string][]*handlers.Check{"checks": checks})
 }
 
 func (h *Handler) GetHandlers(c echo.Context) error {
@@ -81,16 +198,127 @@ func (h *Handler) HandlerSend(c echo.Context) error {
 	return c.JSON(http.StatusOK, map[string]string{"message": "message sent"})
 }
 
+func (h *Handler) GetQueues(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string][]handlers.QueueStats{"queues": h.Dispatcher.Stats()})
+}
+
+func (h *Handler) GetDeadLetters(c echo.Context) error {
+	deadLetters := &[]handlers.DeadLetter{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := h.Storage.GetAllDeadLetters(ctx, deadLetters)
+	if err != nil {
+		m := fmt.Sprintf("Error getting dead letters: %v", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: m})
+	}
+
+	return c.JSON(http.StatusOK, map[string][]handlers.DeadLetter{"dead_letters": *deadLetters})
+}
+
+// ReplayDeadLetter queues a dead letter again to its handler and deletes it once queued
+func (h *Handler) ReplayDeadLetter(c echo.Context) error {
+	id := c.Param("id")
+	dl := &handlers.DeadLetter{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := h.Storage.GetDeadLetter(ctx, id, dl)
+	if err != nil {
+		m := fmt.Sprintf("Error getting dead letter: %v", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: m})
+	}
+
+	if dl.ID == "" {
+		e := fmt.Sprintf("Error dead letter '%s' not found", id)
+		return c.JSON(http.StatusNotFound, ErrorResponse{e})
+	}
+
+	handler, err := h.Handlers.Get(dl.Handler)
+	if err != nil {
+		e := fmt.Sprintf("Error handler '%s' not found", dl.Handler)
+		return c.JSON(http.StatusNotFound, ErrorResponse{e})
+	}
+
+	err = h.Dispatcher.Dispatch(handler, dl.Message)
+	if err != nil {
+		e := fmt.Sprintf("Error queuing message: %v", err)
+		return c.JSON(http.StatusServiceUnavailable, ErrorResponse{e})
+	}
+
+	err = h.Storage.DeleteDeadLetter(ctx, dl.ID)
+	if err != nil {
+		m := fmt.Sprintf("Error deleting dead letter: %v", err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: m})
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]string{"message": "message queued"})
+}
+
+// SlackActions receives the interaction payloads of the buttons of Slack messages
+func (h *Handler) SlackActions(c echo.Context) error {
+	secret := viper.GetString("slack-signing-secret")
+	if secret == "" {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"Slack signing secret not configured"})
+	}
+
+	body, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"bad request"})
+	}
+
+	verifier, err := slack.NewSecretsVerifier(c.Request().Header, secret)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{"invalid signature"})
+	}
+	_, _ = verifier.Write(body)
+	if err := verifier.Ensure(); err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{"invalid signature"})
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"bad request"})
+	}
+
+	callback := &slack.InteractionCallback{}
+	err = json.Unmarshal([]byte(form.Get("payload")), callback)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"invalid payload"})
+	}
+
+	msg, err := handlers.HandleSlackAction(callback)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, msg)
+}
+
 // Start starts the API server
 func Start() {
 	s := server.New()
-	h := &Handler{Storage: viper.Get("storage").(storage.Storage)}
+	h := &Handler{
+		Storage:    viper.Get("storage").(storage.Storage),
+		Checks:     viper.Get("checks").(*registries.Checks),
+		Handlers:   viper.Get("handlers-registry").(*registries.Handlers),
+		Dispatcher: viper.Get("dispatcher").(*handlers.Dispatcher),
+	}
 	r := &router.Router{
 		Routes: []router.Route{
 			{"Root", http.MethodGet, "/", h.root},
 			{"Incidents", http.MethodGet, "/incidents", h.GetIncidents},
+			{"Incident", http.MethodGet, "/incidents/:id", h.GetIncident},
+			{"IncidentDeliveries", http.MethodGet, "/incidents/:id/deliveries", h.GetIncidentDeliveries},
+			{"Checks", http.MethodGet, "/checks", h.GetChecks},
 			{"Handlers", http.MethodGet, "/stats", h.GetHandlers},
+			{"Queues", http.MethodGet, "/queues", h.GetQueues},
+			{"DeadLetters", http.MethodGet, "/dead-letters", h.GetDeadLetters},
+			{"ReplayDeadLetter", http.MethodPost, "/dead-letters/:id/replay", h.ReplayDeadLetter},
 			{"HandlerSend", http.MethodPost, "/handlers/:name/send", h.HandlerSend},
+			{"SlackActions", http.MethodPost, "/slack/actions", h.SlackActions},
 		},
 	}
 
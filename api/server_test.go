@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/uberwachen/handlers"
+	"github.com/alexferl/uberwachen/registries"
+	"github.com/alexferl/uberwachen/storage"
+)
+
+// testStorage holds dead letters and deliveries, the other methods of storage.Storage aren't implemented
+type testStorage struct {
+	storage.Storage
+	deadLetters map[string]*handlers.DeadLetter
+	deliveries  []*handlers.Delivery
+}
+
+func (s *testStorage) GetDeadLetter(ctx context.Context, id string, item interface{}) error {
+	if dl, ok := s.deadLetters[id]; ok {
+		*item.(*handlers.DeadLetter) = *dl
+	}
+	return nil
+}
+
+func (s *testStorage) DeleteDeadLetter(ctx context.Context, id string) error {
+	delete(s.deadLetters, id)
+	return nil
+}
+
+func (s *testStorage) GetDeliveries(ctx context.Context, incidentIDs []string, items interface{}) error {
+	deliveries := items.(*[]*handlers.Delivery)
+	for _, d := range s.deliveries {
+		for _, id := range incidentIDs {
+			if d.IncidentID == id {
+				*deliveries = append(*deliveries, d)
+			}
+		}
+	}
+	return nil
+}
+
+// chanSender sends its messages to a channel
+type chanSender chan *handlers.Message
+
+func (c chanSender) Send(msg *handlers.Message) error {
+	c <- msg
+	return nil
+}
+
+// TestReplayDeadLetter replays the dead letter of a handler used by no check but through a set
+func TestReplayDeadLetter(t *testing.T) {
+	sent := make(chanSender, 1)
+	registry := registries.NewHandlers()
+	registry.Register(&handlers.Handler{Name: "test", Type: "test", Handler: sent})
+	set := handlers.NewSetHandler([]string{"test"})
+	set.Name = "team"
+	registry.Register(set)
+
+	msg := &handlers.Message{Type: handlers.MsgTypeNew, Title: "Check failed"}
+	db := &testStorage{deadLetters: map[string]*handlers.DeadLetter{
+		"1": {ID: "1", Handler: "test", Message: msg},
+		"2": {ID: "2", Handler: "removed", Message: msg},
+	}}
+
+	h := &Handler{Storage: db, Handlers: registry, Dispatcher: handlers.NewDispatcher(10, 1)}
+
+	tests := []struct {
+		id     string
+		status int
+	}{
+		{"1", http.StatusAccepted},
+		{"2", http.StatusNotFound},
+		{"3", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues(tt.id)
+
+		if err := h.ReplayDeadLetter(c); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != tt.status {
+			t.Errorf("dead letter '%s': expected status %d got %d: %s", tt.id, tt.status, rec.Code, rec.Body)
+		}
+	}
+
+	select {
+	case m := <-sent:
+		if m.Title != "Check failed" {
+			t.Errorf("unexpected message %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the replayed message")
+	}
+
+	if _, ok := db.deadLetters["1"]; ok {
+		t.Error("replayed dead letter not deleted")
+	}
+	if _, ok := db.deadLetters["2"]; !ok {
+		t.Error("dead letter of unknown handler deleted")
+	}
+}
+
+func TestGetIncidentDeliveries(t *testing.T) {
+	db := &testStorage{deliveries: []*handlers.Delivery{
+		handlers.NewDelivery("resolved", "slack", handlers.MsgTypeNew, 1, nil),
+		handlers.NewDelivery("resolved", "slack", handlers.MsgTypeResolve, 2, nil),
+		handlers.NewDelivery("other", "slack", handlers.MsgTypeNew, 1, nil),
+	}}
+	h := &Handler{Storage: db}
+
+	tests := []struct {
+		id    string
+		count int
+	}{
+		{"resolved", 2},
+		{"other", 1},
+		{"unknown", 0},
+	}
+
+	for _, tt := range tests {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues(tt.id)
+
+		if err := h.GetIncidentDeliveries(c); err != nil {
+			t.Fatal(err)
+		}
+
+		var body map[string][]*handlers.Delivery
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusOK || len(body["deliveries"]) != tt.count {
+			t.Errorf("incident '%s': expected %d deliveries, got %d %s", tt.id, tt.count, rec.Code, rec.Body)
+		}
+	}
+}
+
+// TestGetChecksWhileRunning encodes the checks while they run, which the race detector checks
+func TestGetChecksWhileRunning(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "check_disk"), []byte("#!/bin/sh\necho disk ok\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("commands-path", dir)
+	viper.Set("storage", &testStorage{})
+
+	check := handlers.NewCheck()
+	check.Name = "disk"
+	check.Command = "check_disk"
+	check.Labels = handlers.Labels{"team": "ops"}
+	registry := registries.NewChecks()
+	if err := registry.Register(check); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{Checks: registry}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			handlers.RunCheck(check)
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/?labels=team=ops", nil), rec)
+		if err := h.GetChecks(c); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", rec.Code, rec.Body)
+		}
+	}
+	wg.Wait()
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	if err := h.GetChecks(e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)); err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string][]*handlers.Check
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body["checks"]) != 1 || body["checks"][0].Output != "disk ok" || len(body["checks"][0].History) != 5 {
+		t.Errorf("unexpected checks %s", rec.Body)
+	}
+}
@@ -12,14 +12,19 @@ import (
 
 // Config holds all configuration for our program
 type Config struct {
-	Config           *xconfig.Config
-	Http             *xhttp.Config
-	Logging          *xlog.Config
-	ChecksPath       string
-	CommandsPath     string
-	HandlersPath     string
-	RunChecksOnStart bool
-	MongoDB          *MongoDB
+	Config             *xconfig.Config
+	Http               *xhttp.Config
+	Logging            *xlog.Config
+	ChecksPath         string
+	CommandsPath       string
+	HandlersPath       string
+	RunChecksOnStart   bool
+	WatchFiles         bool
+	DuplicateChecks    string
+	SlackSigningSecret string
+	QueueSize          int
+	QueueWorkers       int
+	MongoDB            *MongoDB
 }
 
 // MongoDB holds all the configuration for the MongoDB storage
@@ -32,6 +37,7 @@ type MongoDB struct {
 	ConnectTimeout         time.Duration
 	ServerSelectionTimeout time.Duration
 	SocketTimeout          time.Duration
+	DeliveriesTTL          time.Duration
 }
 
 // NewConfig creates a Config instance
@@ -44,6 +50,10 @@ func NewConfig() *Config {
 		CommandsPath:     "./examples/commands",
 		HandlersPath:     "./examples/handlers",
 		RunChecksOnStart: false,
+		WatchFiles:       true,
+		DuplicateChecks:  "error",
+		QueueSize:        100,
+		QueueWorkers:     1,
 		MongoDB: &MongoDB{
 			URI:                    "mongodb://localhost:27017",
 			DatabaseName:           "uberwachen",
@@ -53,6 +63,7 @@ func NewConfig() *Config {
 			ConnectTimeout:         5 * time.Second,
 			ServerSelectionTimeout: 5 * time.Second,
 			SocketTimeout:          30 * time.Second,
+			DeliveriesTTL:          30 * 24 * time.Hour,
 		},
 	}
 }
@@ -67,6 +78,16 @@ func (c *Config) addFlags(fs *pflag.FlagSet) {
 		"Path to the handlers definition files")
 	fs.BoolVar(&c.RunChecksOnStart, "run-checks-on-start", c.RunChecksOnStart,
 		"Run checks when they're first registered and then on their normal schedule")
+	fs.BoolVar(&c.WatchFiles, "watch-files", c.WatchFiles,
+		"Reload checks and handlers when their definition files change")
+	fs.StringVar(&c.DuplicateChecks, "duplicate-checks", c.DuplicateChecks,
+		"What to do with checks defined more than once: 'error', 'first-wins' or 'last-wins'")
+	fs.StringVar(&c.SlackSigningSecret, "slack-signing-secret", c.SlackSigningSecret,
+		"Signing secret of the Slack app verifying the requests of interactive messages")
+	fs.IntVar(&c.QueueSize, "queue-size", c.QueueSize,
+		"Number of messages each handler can have waiting to be sent before new ones are dropped")
+	fs.IntVar(&c.QueueWorkers, "queue-workers", c.QueueWorkers,
+		"Number of workers sending the messages of each handler, the messages of an incident staying in order")
 
 	// MongoDB
 	fs.StringVar(&c.MongoDB.URI, "mongodb-uri", c.MongoDB.URI, "MongoDB URI")
@@ -81,6 +102,8 @@ func (c *Config) addFlags(fs *pflag.FlagSet) {
 		c.MongoDB.ServerSelectionTimeout, "MongoDB server selection timeout")
 	fs.DurationVar(&c.MongoDB.SocketTimeout, "mongodb-socket-timeout", c.MongoDB.SocketTimeout,
 		"MongoDB socket timeout")
+	fs.DurationVar(&c.MongoDB.DeliveriesTTL, "mongodb-deliveries-ttl", c.MongoDB.DeliveriesTTL,
+		"How long the deliveries of incidents are kept, 0 keeps them forever")
 }
 
 func (c *Config) BindFlags() {
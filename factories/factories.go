@@ -1,41 +1,124 @@
 package factories
 
 import (
-	"github.com/rs/zerolog/log"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 
 	"github.com/alexferl/uberwachen/handlers"
 	"github.com/alexferl/uberwachen/storage"
+	"github.com/alexferl/uberwachen/util"
 )
 
+// commonKeys are the handler definition keys handled for every handler type
+var commonKeys = []string{"type", "route", "retry", "templates", "filters"}
+
 // Handler creates a new object with handlers.HandlerSender interface
-func Handler(handlerType string, handlerConfig map[string]interface{}) *handlers.Handler {
-	switch handlerType {
-	case "console":
-		return handlers.NewConsoleHandler()
-
-	case "sendgrid":
-		apiKey := handlerConfig["apiKey"].(string)
-		subjectPrefix := handlerConfig["subjectPrefix"].(string)
-		from := handlerConfig["from"].(string)
-		fromName := handlerConfig["fromName"].(string)
-		to := handlerConfig["to"].(string)
-		toName := handlerConfig["toName"].(string)
-		notifyOnResolve := handlerConfig["notifyOnResolve"].(bool)
-		return handlers.NewSendGridHandler(apiKey, subjectPrefix, from, fromName, to, toName, notifyOnResolve)
-
-	case "slack":
-		channel := handlerConfig["channel"].(string)
-		token := handlerConfig["token"].(string)
-		botUsername := handlerConfig["botUsername"].(string)
-		botIconUrl := handlerConfig["botIconUrl"].(string)
-		return handlers.NewSlackHandler(channel, token, botUsername, botIconUrl)
-
-	default:
-		log.Warn().Msgf("Unknown handler type '%s'", handlerType)
-		var h handlers.Handler
-		return &h
+func Handler(handlerType string, handlerConfig map[string]interface{}) (*handlers.Handler, error) {
+	t, err := handlers.GetType(handlerType)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs util.Errors
+	config := t.Config()
+	errs = errs.Append(decode(handlerConfig, config))
+	errs = errs.Append(validate(config))
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	h, err := t.New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	h.Type = handlerType
+	return h, nil
+}
+
+// decode decodes a handler definition into its typed config
+func decode(handlerConfig map[string]interface{}, config interface{}) error {
+	input := make(map[string]interface{}, len(handlerConfig))
+	for k, v := range handlerConfig {
+		input[k] = v
+	}
+	for _, k := range commonKeys {
+		delete(input, k)
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			stringToAddressesHookFunc(),
+		),
+		ErrorUnused: true,
+		Result:      config,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = decoder.Decode(input)
+	if err != nil {
+		if e, ok := err.(*mapstructure.Error); ok {
+			var errs util.Errors
+			for _, msg := range e.Errors {
+				msg = strings.TrimPrefix(msg, "'' has ")
+				errs = append(errs, errors.New(msg))
+			}
+			return errs
+		}
+		return err
+	}
+
+	return nil
+}
+
+// stringToAddressesHookFunc splits the comma separated strings decoded into handlers.Addresses
+func stringToAddressesHookFunc() mapstructure.DecodeHookFuncType {
+	return func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf(handlers.Addresses{}) {
+			return data, nil
+		}
+
+		addresses := handlers.Addresses{}
+		for _, address := range strings.Split(data.(string), ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+		return addresses, nil
+	}
+}
+
+// validate checks the required fields of a handler config are set and
+// runs its own validation if it implements handlers.Validator
+func validate(config interface{}) error {
+	var errs util.Errors
+
+	v := reflect.ValueOf(config).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Tag.Get("validate") != "required" {
+			continue
+		}
+
+		if v.Field(i).IsZero() {
+			key := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+			errs = append(errs, errors.New(fmt.Sprintf("key '%s' is required", key)))
+		}
 	}
+
+	if validator, ok := config.(handlers.Validator); ok {
+		errs = errs.Append(validator.Validate())
+	}
+
+	return errs.ErrorOrNil()
 }
 
 // Backend creates a new object with handlers.Backend interface
@@ -48,6 +131,7 @@ func Backend() (storage.Storage, error) {
 		ConnectTimeout:         viper.GetDuration("mongodb-connect-timeout"),
 		ServerSelectionTimeout: viper.GetDuration("mongodb-server-selection-timeout"),
 		SocketTimeout:          viper.GetDuration("mongodb-socket-timeout"),
+		DeliveriesTTL:          viper.GetDuration("mongodb-deliveries-ttl"),
 	}
 	return storage.NewMongoDB(opts)
 }
@@ -0,0 +1,137 @@
+package factories
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexferl/uberwachen/handlers"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		config   interface{}
+		expected interface{}
+		err      string
+	}{
+		{
+			name:     "addresses from a list",
+			input:    map[string]interface{}{"type": "sendgrid", "to": []interface{}{"a@example.com", "b@example.com"}},
+			config:   &handlers.SendGridConfig{},
+			expected: &handlers.SendGridConfig{To: handlers.Addresses{"a@example.com", "b@example.com"}},
+		},
+		{
+			name:     "addresses from a string",
+			input:    map[string]interface{}{"to": "a@example.com, b@example.com", "cc": ""},
+			config:   &handlers.SendGridConfig{},
+			expected: &handlers.SendGridConfig{To: handlers.Addresses{"a@example.com", "b@example.com"}, Cc: handlers.Addresses{}},
+		},
+		{
+			name:     "duration",
+			input:    map[string]interface{}{"apiKey": "key", "timeout": "5s"},
+			config:   &handlers.OpsgenieConfig{},
+			expected: &handlers.OpsgenieConfig{ApiKey: "key", Timeout: 5 * time.Second},
+		},
+		{
+			name:   "string into a list",
+			input:  map[string]interface{}{"apiKey": "key", "tags": "a,b"},
+			config: &handlers.OpsgenieConfig{},
+			err:    "'tags': source data must be an array or slice, got string",
+		},
+		{
+			name:   "unknown key",
+			input:  map[string]interface{}{"apiKey": "key", "nope": true},
+			config: &handlers.OpsgenieConfig{},
+			err:    "invalid keys: nope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := decode(tt.input, tt.config)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected error '%s', got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.config, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, tt.config)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		err    []string
+	}{
+		{
+			name:   "console",
+			config: map[string]interface{}{"type": "console", "route": "default"},
+		},
+		{
+			name:   "slack",
+			config: map[string]interface{}{"type": "slack", "channel": "#alerts", "token": "xoxb"},
+		},
+		{
+			name:   "slack",
+			config: map[string]interface{}{"type": "slack", "channel": "#alerts"},
+			err:    []string{"key 'token' is required"},
+		},
+		{
+			name:   "sendgrid",
+			config: map[string]interface{}{"type": "sendgrid", "apiKey": "key", "from": "nope", "nope": 1},
+			err:    []string{"invalid keys: nope", "key 'to' is required", "key 'from': invalid address 'nope'"},
+		},
+		{
+			name:   "set",
+			config: map[string]interface{}{"type": "set", "handlers": []interface{}{"slack"}},
+		},
+		{
+			name:   "set",
+			config: map[string]interface{}{"type": "set", "handlers": []interface{}{}},
+			err:    []string{"key 'handlers': must have at least one handler"},
+		},
+		{
+			name:   "set",
+			config: map[string]interface{}{"type": "set"},
+			err:    []string{"key 'handlers' is required"},
+		},
+		{
+			name: "nope",
+			err:  []string{"unknown handler type 'nope'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := Handler(tt.name, tt.config)
+			if len(tt.err) > 0 {
+				if err == nil {
+					t.Fatalf("expected errors %v", tt.err)
+				}
+				for _, e := range tt.err {
+					if !strings.Contains(err.Error(), e) {
+						t.Errorf("error doesn't contain '%s':\n%v", e, err)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Type != tt.name || h.Handler == nil {
+				t.Errorf("unexpected handler %+v", h)
+			}
+		})
+	}
+}
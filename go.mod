@@ -6,19 +6,22 @@ require (
 	github.com/alexferl/golib/config v0.0.0-20220209021910-e476bf963a39
 	github.com/alexferl/golib/http v0.0.0-20220209021910-e476bf963a39
 	github.com/alexferl/golib/log v0.0.0-20220209021910-e476bf963a39
+	github.com/fsnotify/fsnotify v1.5.1
 	github.com/jpillora/backoff v1.0.0
 	github.com/labstack/echo/v4 v4.6.3
+	github.com/mitchellh/mapstructure v1.4.3
 	github.com/nlopes/slack v0.6.0
+	github.com/pelletier/go-toml v1.9.4
 	github.com/rs/zerolog v1.26.1
 	github.com/sendgrid/sendgrid-go v3.10.5+incompatible
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.10.1
 	github.com/ventu-io/go-shortid v0.0.0-20201117134242-e59966efd125
 	go.mongodb.org/mongo-driver v1.8.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/fsnotify/fsnotify v1.5.1 // indirect
 	github.com/go-stack/stack v1.8.0 // indirect
 	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
 	github.com/golang/snappy v0.0.1 // indirect
@@ -29,8 +32,6 @@ require (
 	github.com/magiconair/properties v1.8.5 // indirect
 	github.com/mattn/go-colorable v0.1.12 // indirect
 	github.com/mattn/go-isatty v0.0.14 // indirect
-	github.com/mitchellh/mapstructure v1.4.3 // indirect
-	github.com/pelletier/go-toml v1.9.4 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/sendgrid/rest v2.6.7+incompatible // indirect
 	github.com/spf13/afero v1.6.0 // indirect
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -11,6 +13,27 @@ import (
 	"github.com/alexferl/uberwachen/util"
 )
 
+const (
+	StatusOK       = 0
+	StatusWarning  = 1
+	StatusCritical = 2
+	StatusUnknown  = 3
+)
+
+// StatusName returns the name of a check exit status
+func StatusName(status int) string {
+	switch status {
+	case StatusOK:
+		return "OK"
+	case StatusWarning:
+		return "WARNING"
+	case StatusCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 // CheckLoad is used to load a check from a file,
 // and then it gets converted to a Check
 type CheckLoad struct {
@@ -20,10 +43,14 @@ type CheckLoad struct {
 	MaxAttempts  int      `json:"max_attempts" bson:"max_attempts"`
 	HandlerNames []string `json:"handlers" bson:"handlers"`
 	Renotify     bool     `json:"renotify"`
+	Labels       Labels   `json:"labels"`
+	RunbookURL   string   `json:"runbook_url" bson:"runbook_url"`
 }
 
-// Check represents a check
+// Check represents a check. Its results and handlers are guarded by a lock
+// as they're changed while it runs, Snapshot returning a copy of them.
 type Check struct {
+	mu             sync.RWMutex
 	*CheckLoad     `bson:",inline"`
 	Attempts       int        `json:"attempts"`
 	Duration       float64    `json:"duration"`
@@ -34,6 +61,55 @@ type Check struct {
 	Output         string     `json:"output"`
 	Status         int        `json:"status"`
 	Handlers       []*Handler `json:"-" bson:"-"`
+	Source         *Source    `json:"source,omitempty" bson:"source,omitempty"`
+}
+
+// Source is where a check is defined
+type Source struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+func (s *Source) String() string {
+	if s.Line > 0 {
+		return fmt.Sprintf("%s:%d", s.File, s.Line)
+	}
+	return s.File
+}
+
+// GetHandlers returns the handlers of a check
+func (c *Check) GetHandlers() []*Handler {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Handlers
+}
+
+// SetHandlers replaces the handlers and source of a check, which are swapped by reloads while it runs
+func (c *Check) SetHandlers(handlers []*Handler, source *Source) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Handlers = handlers
+	c.Source = source
+}
+
+// Snapshot returns a copy of a check taken while it isn't being changed
+func (c *Check) Snapshot() *Check {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &Check{
+		CheckLoad:      c.CheckLoad,
+		Attempts:       c.Attempts,
+		Duration:       c.Duration,
+		ExecutedAt:     c.ExecutedAt,
+		History:        append([]int(nil), c.History...),
+		IssuedAt:       c.IssuedAt,
+		PreviousOutput: c.PreviousOutput,
+		Output:         c.Output,
+		Status:         c.Status,
+		Handlers:       c.Handlers,
+		Source:         c.Source,
+	}
 }
 
 // NewCheck creates a new Check
@@ -48,7 +124,7 @@ func RunCheck(c *Check) {
 	cmdPath := util.GetCmdPath(c.Command)
 	_, cmdArgs := util.SplitCmd(c.Command)
 
-	c.IssuedAt = time.Now().UTC()
+	issuedAt := time.Now().UTC()
 	code := 0
 	output, err := exec.Command(cmdPath, cmdArgs...).CombinedOutput()
 	if err != nil {
@@ -60,6 +136,8 @@ func RunCheck(c *Check) {
 		}
 	}
 
+	c.mu.Lock()
+	c.IssuedAt = issuedAt
 	c.Status = code
 	c.Output = strings.TrimSuffix(string(output), "\n")
 	c.ExecutedAt = time.Now().UTC()
@@ -70,6 +148,7 @@ func RunCheck(c *Check) {
 	if len(c.History) > 10 {
 		c.History = c.History[:10] // Don't need more than 10 statuses
 	}
+	c.mu.Unlock()
 
 	log.Debug().Msgf("Ran check '%s': exit code: '%d' duration: '%.3f' output: '%s'",
 		c.Name, c.Status, c.Duration, c.Output)
@@ -9,6 +9,19 @@ type consoleHandler struct {
 	*Handler
 }
 
+// ConsoleConfig holds the configuration of a console handler
+type ConsoleConfig struct{}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "console",
+		Config: func() interface{} { return &ConsoleConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewConsoleHandler(), nil
+		},
+	})
+}
+
 // NewConsoleHandler creates a consoleHandler instance
 func NewConsoleHandler() *Handler {
 	return &Handler{
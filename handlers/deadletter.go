@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/uberwachen/storage"
+	"github.com/alexferl/uberwachen/util"
+)
+
+// DeadLetter is a message a handler failed to send after all its attempts
+type DeadLetter struct {
+	ID       string    `json:"id" bson:"_id"`
+	Handler  string    `json:"handler"`
+	Message  *Message  `json:"message"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at" bson:"failed_at"`
+}
+
+// NewDeadLetter creates a DeadLetter instance
+func NewDeadLetter(handler string, msg *Message, attempts int, err error) *DeadLetter {
+	return &DeadLetter{
+		ID:       util.GenerateShortId(),
+		Handler:  handler,
+		Message:  msg,
+		Attempts: attempts,
+		Error:    err.Error(),
+		FailedAt: time.Now().UTC(),
+	}
+}
+
+// Save saves the dead letter to the database
+func (dl *DeadLetter) Save() error {
+	db := viper.Get("storage").(storage.Storage)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return db.SetDeadLetter(ctx, dl)
+}
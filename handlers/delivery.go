@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/uberwachen/storage"
+)
+
+// Delivery records whether a handler sent a message of an incident
+type Delivery struct {
+	IncidentID string    `json:"-" bson:"incident_id"`
+	Handler    string    `json:"handler"`
+	Type       string    `json:"type"`
+	Attempts   int       `json:"attempts"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// NewDelivery creates a Delivery instance
+func NewDelivery(incidentID, handler, msgType string, attempts int, err error) *Delivery {
+	d := &Delivery{
+		IncidentID: incidentID,
+		Handler:    handler,
+		Type:       msgType,
+		Attempts:   attempts,
+		Success:    err == nil,
+		Timestamp:  time.Now().UTC(),
+	}
+
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	return d
+}
+
+// Save saves the delivery to the database
+func (d *Delivery) Save() error {
+	db := viper.Get("storage").(storage.Storage)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return db.SetDelivery(ctx, d)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// discordMaxTitle and discordMaxDescription are the maximum lengths of the title and description of an embed
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+)
+
+// discordHandler represents a Discord webhook handler
+type discordHandler struct {
+	*Handler
+	URL       string `json:"-" bson:"-"`
+	Username  string `json:"username"`
+	AvatarUrl string `json:"avatar_url" bson:"avatar_url"`
+	client    *http.Client
+}
+
+// DiscordConfig holds the configuration of a Discord handler
+type DiscordConfig struct {
+	URL       string        `mapstructure:"url" validate:"required"`
+	Username  string        `mapstructure:"username"`
+	AvatarUrl string        `mapstructure:"avatarUrl"`
+	Timeout   time.Duration `mapstructure:"timeout"`
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "discord",
+		Config: func() interface{} { return &DiscordConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewDiscordHandler(config.(*DiscordConfig)), nil
+		},
+	})
+}
+
+// NewDiscordHandler creates a discordHandler instance
+func NewDiscordHandler(config *DiscordConfig) *Handler {
+	d := &discordHandler{
+		URL:       config.URL,
+		Username:  config.Username,
+		AvatarUrl: config.AvatarUrl,
+		client:    &http.Client{Timeout: config.Timeout},
+	}
+
+	if d.client.Timeout == 0 {
+		d.client.Timeout = defaultHTTPTimeout
+	}
+
+	return &Handler{
+		Type:    "discord",
+		Handler: d,
+	}
+}
+
+type discordEmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type discordEmbed struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	Color       int                 `json:"color,omitempty"`
+	Fields      []discordEmbedField `json:"fields,omitempty"`
+	Timestamp   string              `json:"timestamp,omitempty"`
+}
+
+type discordMessage struct {
+	Username  string          `json:"username,omitempty"`
+	AvatarUrl string          `json:"avatar_url,omitempty"`
+	Embeds    []*discordEmbed `json:"embeds"`
+}
+
+// Send posts an embed to a Discord channel
+func (d *discordHandler) Send(msg *Message) error {
+	embed := &discordEmbed{
+		Title:     truncate(msg.Title, discordMaxTitle),
+		Color:     messageColorInt(msg.Type),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if msg.Body != "" {
+		// The code block around the output takes 8 characters of the description
+		body := truncate(msg.Body, discordMaxDescription-8)
+		embed.Description = fmt.Sprintf("```\n%s\n```", body)
+	}
+
+	for _, f := range messageFacts(msg) {
+		embed.Fields = append(embed.Fields, discordEmbedField{Name: f.Name, Value: f.Value, Inline: true})
+	}
+
+	b, err := json.Marshal(&discordMessage{
+		Username:  d.Username,
+		AvatarUrl: d.AvatarUrl,
+		Embeds:    []*discordEmbed{embed},
+	})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	_, err = doRequest(d.client, http.MethodPost, d.URL, headers, b)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msg("Message successfully sent to Discord")
+	return nil
+}
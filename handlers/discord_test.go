@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDiscordHandlerSend(t *testing.T) {
+	var received discordMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if !utf8.Valid(b) {
+			t.Error("payload is not valid UTF-8")
+		}
+		if err := json.Unmarshal(b, &received); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	h := NewDiscordHandler(&DiscordConfig{URL: srv.URL, Username: "uberwachen"})
+
+	msg := &Message{
+		Type:      MsgTypeNew,
+		Title:     strings.Repeat("é", 300),
+		Body:      strings.Repeat("日", 5000),
+		CheckName: "disk",
+		Severity:  "CRITICAL",
+	}
+	if err := h.Handler.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received.Embeds) != 1 {
+		t.Fatalf("expected 1 embed, got %d", len(received.Embeds))
+	}
+	embed := received.Embeds[0]
+
+	if n := utf8.RuneCountInString(embed.Title); n != discordMaxTitle {
+		t.Errorf("expected title of %d characters, got %d", discordMaxTitle, n)
+	}
+	if n := utf8.RuneCountInString(embed.Description); n != discordMaxDescription {
+		t.Errorf("expected description of %d characters, got %d", discordMaxDescription, n)
+	}
+	if embed.Color != 0xDF0101 {
+		t.Errorf("unexpected color %d", embed.Color)
+	}
+	if received.Username != "uberwachen" {
+		t.Errorf("unexpected username '%s'", received.Username)
+	}
+	if len(embed.Fields) == 0 || embed.Fields[0].Name != "Check" || embed.Fields[0].Value != "disk" {
+		t.Errorf("unexpected fields %v", embed.Fields)
+	}
+}
+
+func TestDiscordHandlerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	h := NewDiscordHandler(&DiscordConfig{URL: srv.URL})
+	if err := h.Handler.Send(&Message{Type: MsgTypeNew, Title: "title"}); err == nil {
+		t.Error("expected an error on a bad request")
+	}
+}
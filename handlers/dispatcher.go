@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jpillora/backoff"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultQueueSize    = 100
+	defaultQueueWorkers = 1
+)
+
+// job is a message waiting to be sent by a handler. A job opens the incident
+// of its message for the handler when it's the first message of the incident
+// the handler sends, the next ones following it up.
+type job struct {
+	handler  *Handler
+	msg      *Message
+	opens    bool
+	attempts int
+	backoff  *backoff.Backoff
+}
+
+// queue holds the messages of a handler and the counters of what happened to them.
+// The messages of an incident are sent one after the other in the order they're
+// queued, the ones queued while another one of their incident is being sent
+// waiting in the lane of the incident.
+type queue struct {
+	mu      sync.Mutex
+	jobs    chan *job
+	pending int
+	lanes   map[string][]*job
+	closed  bool
+	sent    uint64
+	failed  uint64
+	dropped uint64
+}
+
+// QueueStats are the metrics of the queue of a handler
+type QueueStats struct {
+	Handler  string `json:"handler"`
+	Pending  int    `json:"pending"`
+	Capacity int    `json:"capacity"`
+	Sent     uint64 `json:"sent"`
+	Failed   uint64 `json:"failed"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// Dispatcher sends messages asynchronously through a bounded queue per handler,
+// each one consumed by its own workers, so sending never blocks checks.
+// Messages are dropped when the queue of their handler is full, and saved
+// as dead letters when their handler fails to send them after retrying.
+// Failed messages wait for their next attempt outside of the workers.
+// The messages of an incident are sent in order even with several workers.
+// The queues of handlers that are gone are closed once their messages are sent.
+type Dispatcher struct {
+	mu      sync.Mutex
+	size    int
+	workers int
+	queues  map[string]*queue
+}
+
+// NewDispatcher creates a Dispatcher with queues of the given size and number of workers
+func NewDispatcher(size, workers int) *Dispatcher {
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	if workers <= 0 {
+		workers = defaultQueueWorkers
+	}
+
+	return &Dispatcher{
+		size:    size,
+		workers: workers,
+		queues:  make(map[string]*queue),
+	}
+}
+
+// Dispatch queues a message to be sent by a handler without waiting for it to be sent,
+// it returns an error when the queue of the handler is full. The handler is saved as
+// notified on the incident of the message until sending it fails.
+func (d *Dispatcher) Dispatch(handler *Handler, msg *Message) error {
+	m := msg.clone()
+	if handler.Templates != nil {
+		err := handler.Templates.render(m)
+		if err != nil {
+			log.Error().Msgf("Error rendering message of handler '%s', using the default one: %v", handler.Name, err)
+		}
+	}
+
+	j := &job{handler: handler, msg: m}
+	if m.Incident != nil && m.Type != MsgTypeResolve {
+		notified := m.Incident.notified(handler.Name)
+		j.opens = m.Type == MsgTypeNew || !notified
+		if !notified {
+			j.setNotified(true)
+		}
+	}
+
+	q, ok := d.push(j)
+	if !ok {
+		atomic.AddUint64(&q.dropped, 1)
+		log.Error().Msgf("Queue of handler '%s' is full, dropping '%s' message of check '%s'",
+			handler.Name, msg.Type, checkName(msg))
+		if j.opens {
+			j.setNotified(false)
+		}
+		return errors.New(fmt.Sprintf("queue of handler '%s' is full", handler.Name))
+	}
+
+	return nil
+}
+
+// Stats returns the metrics of the queue of every handler, sorted by handler name
+func (d *Dispatcher) Stats() []QueueStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	stats := []QueueStats{}
+	for name, q := range d.queues {
+		q.mu.Lock()
+		pending := q.pending
+		q.mu.Unlock()
+
+		stats = append(stats, QueueStats{
+			Handler:  name,
+			Pending:  pending,
+			Capacity: cap(q.jobs),
+			Sent:     atomic.LoadUint64(&q.sent),
+			Failed:   atomic.LoadUint64(&q.failed),
+			Dropped:  atomic.LoadUint64(&q.dropped),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Handler < stats[j].Handler
+	})
+
+	return stats
+}
+
+// Prune closes and removes the queues of the handlers not in the given names,
+// their workers stopping once the messages already queued are sent
+func (d *Dispatcher) Prune(names []string) {
+	keep := make(map[string]bool, len(names))
+	for _, name := range names {
+		keep[name] = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for name, q := range d.queues {
+		if !keep[name] {
+			log.Debug().Msgf("Handler '%s' removed, closing its queue", name)
+			q.close()
+			delete(d.queues, name)
+		}
+	}
+}
+
+// push queues a job to the queue of its handler, which can't be closed meanwhile
+func (d *Dispatcher) push(j *job) (*queue, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	q := d.queue(j.handler.Name)
+	return q, q.push(j)
+}
+
+// queue returns the queue of a handler, starting its workers on first use.
+// It must be called with the lock held.
+func (d *Dispatcher) queue(name string) *queue {
+	if q, ok := d.queues[name]; ok {
+		return q
+	}
+
+	q := &queue{jobs: make(chan *job, d.size), lanes: make(map[string][]*job)}
+	d.queues[name] = q
+	for i := 0; i < d.workers; i++ {
+		go d.work(q)
+	}
+
+	return q
+}
+
+func (d *Dispatcher) work(q *queue) {
+	for j := range q.jobs {
+		j.attempts++
+		err := j.handler.Handler.Send(j.msg)
+		if err != nil && q.retry(j, err) {
+			continue
+		}
+
+		if j.msg.Incident != nil {
+			e := NewDelivery(j.msg.Incident.ID, j.handler.Name, j.msg.Type, j.attempts, err).Save()
+			if e != nil {
+				log.Error().Msgf("Error saving delivery to database: %v", e)
+			}
+		}
+
+		// the handler is unset when sending the message opening the incident failed,
+		// and set again once replayed
+		if j.opens && (err != nil || !j.msg.Incident.notified(j.handler.Name)) {
+			j.setNotified(err == nil)
+		}
+
+		if err != nil {
+			atomic.AddUint64(&q.failed, 1)
+			log.Error().Msgf("Error sending message with handler '%s' after %d attempt(s): %v",
+				j.handler.Name, j.attempts, err)
+
+			e := NewDeadLetter(j.handler.Name, j.msg, j.attempts, err).Save()
+			if e != nil {
+				log.Error().Msgf("Error saving dead letter to database: %v", e)
+			}
+		} else {
+			atomic.AddUint64(&q.sent, 1)
+		}
+
+		q.done(j, err)
+	}
+}
+
+// push queues a job, or holds it in the lane of its incident while another
+// message of the incident is being sent. It returns false when the queue is full.
+func (q *queue) push(j *job) bool {
+	q.mu.Lock()
+	if q.pending >= cap(q.jobs) {
+		q.mu.Unlock()
+		return false
+	}
+	q.pending++
+
+	if id := j.msg.IncidentID; id != "" {
+		if waiting, busy := q.lanes[id]; busy {
+			q.lanes[id] = append(waiting, j)
+			q.mu.Unlock()
+			return true
+		}
+		q.lanes[id] = nil
+	}
+	q.mu.Unlock()
+
+	// never blocks as there are never more pending jobs than the channel holds
+	q.jobs <- j
+	return true
+}
+
+// done releases a sent job and queues the next message of its incident. When the
+// message opening an incident failed, the next ones following it up are dropped
+// and saved as dead letters, so they can be replayed after it.
+func (q *queue) done(j *job, err error) {
+	q.mu.Lock()
+	q.pending--
+
+	var next *job
+	var skipped []*job
+	if id := j.msg.IncidentID; id != "" {
+		waiting := q.lanes[id]
+		if err != nil && j.opens && len(waiting) > 0 {
+			q.pending -= len(waiting)
+			skipped = waiting
+			waiting = nil
+		}
+
+		if len(waiting) > 0 {
+			next = waiting[0]
+			q.lanes[id] = waiting[1:]
+		} else {
+			delete(q.lanes, id)
+		}
+	}
+	if q.closed && q.pending == 0 {
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	if len(skipped) > 0 {
+		log.Warn().Msgf("First message of incident '%s' not sent by handler '%s', dropping %d message(s)",
+			j.msg.IncidentID, j.handler.Name, len(skipped))
+
+		skipErr := errors.New(fmt.Sprintf("first message of incident '%s' not sent", j.msg.IncidentID))
+		for _, s := range skipped {
+			e := NewDeadLetter(s.handler.Name, s.msg, s.attempts, skipErr).Save()
+			if e != nil {
+				log.Error().Msgf("Error saving dead letter to database: %v", e)
+			}
+		}
+		atomic.AddUint64(&q.dropped, uint64(len(skipped)))
+	}
+
+	if next != nil {
+		next.msg.follow(j.msg)
+		q.jobs <- next
+	}
+}
+
+// close stops the workers of the queue once its pending jobs are done. Jobs
+// are still sent to the channel until then, by retries and incident lanes.
+func (q *queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	if q.pending == 0 {
+		close(q.jobs)
+	}
+}
+
+// setNotified saves whether the handler of a job sent a message of its incident
+func (j *job) setNotified(notified bool) {
+	err := j.msg.Incident.setNotified(j.handler.Name, notified)
+	if err != nil {
+		log.Error().Msgf("Error saving handlers of incident '%s': %v", j.msg.IncidentID, err)
+	}
+}
+
+// clone copies a message with its check and incident, so it
+// isn't changed by the next runs of the check while it's queued
+func (m *Message) clone() *Message {
+	c := *m
+
+	if m.Check != nil {
+		c.Check = m.Check.Snapshot()
+	}
+
+	if m.Incident != nil {
+		incident := *m.Incident
+		if m.Incident.Check != nil {
+			incident.Check = m.Incident.Check.Snapshot()
+		}
+		if m.Incident.Notified != nil {
+			incident.Notified = make(map[string]bool, len(m.Incident.Notified))
+			for k, v := range m.Incident.Notified {
+				incident.Notified[k] = v
+			}
+		}
+		if m.Incident.SlackMessages != nil {
+			incident.SlackMessages = make(map[string]*SlackMessage, len(m.Incident.SlackMessages))
+			for k, v := range m.Incident.SlackMessages {
+				incident.SlackMessages[k] = v
+			}
+		}
+		c.Incident = &incident
+	}
+
+	return &c
+}
+
+// follow copies to a message what the previous message of its incident saved
+// on the incident while being sent, like the Slack message updates are threaded in
+func (m *Message) follow(prev *Message) {
+	if m.Incident == nil || prev.Incident == nil {
+		return
+	}
+
+	for k, v := range prev.Incident.SlackMessages {
+		if m.Incident.SlackMessages == nil {
+			m.Incident.SlackMessages = make(map[string]*SlackMessage)
+		}
+		if _, ok := m.Incident.SlackMessages[k]; !ok {
+			m.Incident.SlackMessages[k] = v
+		}
+	}
+}
+
+func checkName(msg *Message) string {
+	if msg.Check == nil {
+		return ""
+	}
+	return msg.Check.Name
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordSender records the messages it sends, calling its send function first if set
+type recordSender struct {
+	mu   sync.Mutex
+	msgs []*Message
+	send func(msg *Message) error
+	sent chan *Message
+}
+
+func newRecordSender(send func(msg *Message) error) *recordSender {
+	return &recordSender{send: send, sent: make(chan *Message, 100)}
+}
+
+func (r *recordSender) Send(msg *Message) error {
+	if r.send != nil {
+		if err := r.send(msg); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.msgs = append(r.msgs, msg)
+	r.mu.Unlock()
+	r.sent <- msg
+	return nil
+}
+
+// wait waits for n messages to be sent
+func (r *recordSender) wait(t *testing.T, n int) []*Message {
+	t.Helper()
+
+	var msgs []*Message
+	for i := 0; i < n; i++ {
+		select {
+		case msg := <-r.sent:
+			msgs = append(msgs, msg)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for message %d/%d", i+1, n)
+		}
+	}
+	return msgs
+}
+
+func testMessage(msgType, incidentID string) *Message {
+	c := NewCheck()
+	c.Name = "check-" + incidentID
+	incident := NewIncident(c)
+	incident.ID = incidentID
+	msg := NewMessage(msgType, c, incident)
+	return msg
+}
+
+func TestDispatcherIncidentOrder(t *testing.T) {
+	store.reset()
+	d := NewDispatcher(100, 4)
+
+	sender := newRecordSender(func(msg *Message) error {
+		// the new messages being slow, the workers would send the next ones first
+		if msg.Type == MsgTypeNew {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return nil
+	})
+	h := &Handler{Name: "test", Type: "test", Handler: sender}
+
+	incidents := []string{"a", "b", "c"}
+	for _, msgType := range []string{MsgTypeNew, MsgTypeUpdate, MsgTypeResolve} {
+		for _, id := range incidents {
+			if err := d.Dispatch(h, testMessage(msgType, id)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	msgs := sender.wait(t, 9)
+	order := map[string][]string{}
+	for _, msg := range msgs {
+		order[msg.IncidentID] = append(order[msg.IncidentID], msg.Type)
+	}
+
+	for _, id := range incidents {
+		if fmt.Sprint(order[id]) != fmt.Sprint([]string{MsgTypeNew, MsgTypeUpdate, MsgTypeResolve}) {
+			t.Errorf("incident '%s': messages sent out of order: %v", id, order[id])
+		}
+	}
+}
+
+func TestDispatcherQueueFull(t *testing.T) {
+	store.reset()
+	d := NewDispatcher(2, 1)
+
+	release := make(chan struct{})
+	sender := newRecordSender(func(msg *Message) error {
+		<-release
+		return nil
+	})
+	h := &Handler{Name: "blocked", Type: "test", Handler: sender}
+
+	for i := 0; i < 2; i++ {
+		if err := d.Dispatch(h, testMessage(MsgTypeNew, fmt.Sprint(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.Dispatch(h, testMessage(MsgTypeNew, "2")); err == nil {
+		t.Error("expected the third message to be dropped")
+	}
+
+	stats := d.Stats()
+	if len(stats) != 1 || stats[0].Pending != 2 || stats[0].Capacity != 2 || stats[0].Dropped != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	close(release)
+	sender.wait(t, 2)
+}
+
+func TestDispatcherFollowsIncidentState(t *testing.T) {
+	store.reset()
+	d := NewDispatcher(10, 1)
+
+	sender := newRecordSender(func(msg *Message) error {
+		if msg.Type == MsgTypeNew {
+			time.Sleep(20 * time.Millisecond)
+			msg.Incident.SlackMessages = map[string]*SlackMessage{"slack": {Channel: "C1", Timestamp: "1.1"}}
+		}
+		return nil
+	})
+	h := &Handler{Name: "slack", Type: "test", Handler: sender}
+
+	d.Dispatch(h, testMessage(MsgTypeNew, "a"))
+	d.Dispatch(h, testMessage(MsgTypeResolve, "a"))
+
+	msgs := sender.wait(t, 2)
+	if m := msgs[1].Incident.SlackMessages["slack"]; m == nil || m.Timestamp != "1.1" {
+		t.Errorf("resolve message doesn't have the Slack message of the new one: %v", msgs[1].Incident.SlackMessages)
+	}
+}
+
+func TestDispatcherDeadLetter(t *testing.T) {
+	store.reset()
+	d := NewDispatcher(10, 1)
+
+	sender := newRecordSender(func(msg *Message) error {
+		return errors.New("unavailable")
+	})
+	h := &Handler{
+		Name:    "failing",
+		Type:    "test",
+		Handler: sender,
+		Retry:   &RetryPolicy{Attempts: 2, MinDelay: time.Millisecond, MaxDelay: time.Millisecond, Factor: 1},
+	}
+
+	d.Dispatch(h, testMessage(MsgTypeNew, "a"))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var dls []DeadLetter
+		if err := store.GetAllDeadLetters(nil, &dls); err != nil {
+			t.Fatal(err)
+		}
+		if len(dls) == 1 {
+			if dls[0].Handler != "failing" || dls[0].Attempts != 2 || dls[0].Error != "unavailable" {
+				t.Errorf("unexpected dead letter %+v", dls[0])
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the dead letter")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var deliveries []*Delivery
+	if err := store.GetDeliveries(nil, []string{"a"}, &deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Success || deliveries[0].Attempts != 2 {
+		t.Errorf("unexpected deliveries %+v", deliveries)
+	}
+
+	waitStats(t, d, func(s QueueStats) bool { return s.Failed == 1 && s.Pending == 0 })
+}
+
+func TestDispatcherRetryReleasesWorker(t *testing.T) {
+	store.reset()
+	d := NewDispatcher(10, 1)
+
+	var mu sync.Mutex
+	failed := false
+	sender := newRecordSender(func(msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if msg.IncidentID == "a" && !failed {
+			failed = true
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	h := &Handler{
+		Name:    "retrying",
+		Type:    "test",
+		Handler: sender,
+		Retry:   &RetryPolicy{Attempts: 2, MinDelay: 200 * time.Millisecond, MaxDelay: 200 * time.Millisecond, Factor: 1},
+	}
+
+	d.Dispatch(h, testMessage(MsgTypeNew, "a"))
+	d.Dispatch(h, testMessage(MsgTypeUpdate, "a"))
+	d.Dispatch(h, testMessage(MsgTypeNew, "b"))
+
+	msgs := sender.wait(t, 3)
+	var sent []string
+	for _, msg := range msgs {
+		sent = append(sent, msg.IncidentID+":"+msg.Type)
+	}
+	expected := []string{"b:" + MsgTypeNew, "a:" + MsgTypeNew, "a:" + MsgTypeUpdate}
+	if fmt.Sprint(sent) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, sent)
+	}
+
+	var deliveries []*Delivery
+	if err := store.GetDeliveries(nil, []string{"a"}, &deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) == 0 || !deliveries[0].Success || deliveries[0].Attempts != 2 {
+		t.Errorf("unexpected deliveries %+v", deliveries)
+	}
+}
+
+func TestDispatcherSkipsFollowUpsOfFailedNew(t *testing.T) {
+	store.reset()
+	d := NewDispatcher(10, 1)
+
+	var mu sync.Mutex
+	var types []string
+	sender := newRecordSender(func(msg *Message) error {
+		mu.Lock()
+		types = append(types, msg.IncidentID+":"+msg.Type)
+		mu.Unlock()
+		if msg.IncidentID == "a" {
+			time.Sleep(20 * time.Millisecond)
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	h := &Handler{Name: "failing", Type: "test", Handler: sender, Retry: &RetryPolicy{Attempts: 1}}
+
+	d.Dispatch(h, testMessage(MsgTypeNew, "a"))
+	d.Dispatch(h, testMessage(MsgTypeUpdate, "a"))
+	d.Dispatch(h, testMessage(MsgTypeResolve, "a"))
+	d.Dispatch(h, testMessage(MsgTypeNew, "b"))
+
+	sender.wait(t, 1)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(types) != fmt.Sprint([]string{"a:" + MsgTypeNew, "b:" + MsgTypeNew}) {
+		t.Errorf("unexpected messages sent %v", types)
+	}
+	waitStats(t, d, func(s QueueStats) bool {
+		return s.Pending == 0 && s.Failed == 1 && s.Sent == 1 && s.Dropped == 2
+	})
+
+	var dls []DeadLetter
+	if err := store.GetAllDeadLetters(nil, &dls); err != nil {
+		t.Fatal(err)
+	}
+	var dropped []string
+	for _, dl := range dls {
+		if dl.Message.IncidentID != "a" {
+			t.Errorf("unexpected dead letter %+v", dl)
+		}
+		if dl.Message.Type != MsgTypeNew {
+			dropped = append(dropped, dl.Message.Type)
+		}
+	}
+	sort.Strings(dropped)
+	if len(dls) != 3 || fmt.Sprint(dropped) != fmt.Sprint([]string{MsgTypeResolve, MsgTypeUpdate}) {
+		t.Errorf("unexpected dead letters %+v", dls)
+	}
+}
+
+func TestDispatcherPrune(t *testing.T) {
+	store.reset()
+	d := NewDispatcher(10, 1)
+
+	release := make(chan struct{})
+	gone := newRecordSender(func(msg *Message) error {
+		<-release
+		return nil
+	})
+	kept := newRecordSender(nil)
+	goneHandler := &Handler{Name: "gone", Type: "test", Handler: gone}
+	keptHandler := &Handler{Name: "kept", Type: "test", Handler: kept}
+
+	d.Dispatch(keptHandler, testMessage(MsgTypeNew, "a"))
+	d.Dispatch(goneHandler, testMessage(MsgTypeNew, "a"))
+	d.Dispatch(goneHandler, testMessage(MsgTypeNew, "b"))
+	kept.wait(t, 1)
+
+	d.mu.Lock()
+	q := d.queues["gone"]
+	d.mu.Unlock()
+
+	d.Prune([]string{"kept"})
+	stats := d.Stats()
+	if len(stats) != 1 || stats[0].Handler != "kept" {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// the messages already queued are still sent before the queue is closed
+	close(release)
+	gone.wait(t, 2)
+	select {
+	case _, open := <-q.jobs:
+		if open {
+			t.Error("expected the queue to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the queue to be closed")
+	}
+
+	d.Dispatch(goneHandler, testMessage(MsgTypeNew, "c"))
+	gone.wait(t, 1)
+}
+
+// waitStats waits for the stats of the only queue of a dispatcher to be as expected
+func waitStats(t *testing.T, d *Dispatcher, expected func(s QueueStats) bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := d.Stats()
+		if len(stats) == 1 && expected(stats[0]) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected stats %+v", stats)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -14,19 +14,77 @@ import (
 
 const (
 	MsgTypeNew     = "new"
+	MsgTypeUpdate  = "update"
 	MsgTypeResolve = "resolve"
 )
 
 type Event struct {
 	*Check
 	ID        string    `json:"id" bson:"_id"`
+	Labels    Labels    `json:"labels"`
 	CreatedAt time.Time `json:"created_at" bson:"created_at"`
 }
 
+// Message is a notification sent by the handlers. Besides its rendered
+// title and body, it holds the details of its check and incident.
 type Message struct {
-	Body  string `json:"body"`
-	Title string `json:"title"`
-	Type  string `json:"type"`
+	ID             string    `json:"id,omitempty" bson:"id,omitempty"`
+	Body           string    `json:"body"`
+	Title          string    `json:"title"`
+	Type           string    `json:"type"`
+	CheckName      string    `json:"check_name,omitempty" bson:"check_name,omitempty"`
+	IncidentID     string    `json:"incident_id,omitempty" bson:"incident_id,omitempty"`
+	Severity       string    `json:"severity,omitempty" bson:"severity,omitempty"`
+	Status         int       `json:"status"`
+	PreviousStatus int       `json:"previous_status" bson:"previous_status"`
+	Duration       float64   `json:"duration"`
+	Attempts       int       `json:"attempts"`
+	Labels         Labels    `json:"labels,omitempty" bson:"labels,omitempty"`
+	RunbookURL     string    `json:"runbook_url,omitempty" bson:"runbook_url,omitempty"`
+	StartedAt      time.Time `json:"started_at,omitempty" bson:"started_at,omitempty"`
+	ExecutedAt     time.Time `json:"executed_at,omitempty" bson:"executed_at,omitempty"`
+	Check          *Check    `json:"check,omitempty"`
+	Incident       *Incident `json:"incident,omitempty"`
+}
+
+// NewMessage creates a message of the given type rendered with the default templates
+func NewMessage(msgType string, c *Check, incident *Incident) *Message {
+	msg := &Message{
+		ID:         util.GenerateShortId(),
+		Type:       msgType,
+		CheckName:  c.Name,
+		IncidentID: incident.ID,
+		Severity:   StatusName(c.Status),
+		Status:     c.Status,
+		Duration:   c.Duration,
+		Attempts:   incident.Attempts,
+		Labels:     c.Labels,
+		RunbookURL: c.RunbookURL,
+		StartedAt:  incident.CreatedAt,
+		ExecutedAt: c.ExecutedAt,
+		Check:      c,
+		Incident:   incident,
+	}
+
+	if len(c.History) > 1 {
+		msg.PreviousStatus = c.History[1]
+	}
+
+	err := defaultTemplates.render(msg)
+	if err != nil {
+		msg.Title = fmt.Sprintf("Check '%s': %s", c.Name, msgType)
+		msg.Body = c.Output
+	}
+
+	return msg
+}
+
+// IncidentDuration returns how long the incident of a message has been open
+func (m *Message) IncidentDuration() time.Duration {
+	if m.StartedAt.IsZero() {
+		return 0
+	}
+	return time.Since(m.StartedAt)
 }
 
 func NewEvent(c *Check) *Event {
@@ -34,6 +92,7 @@ func NewEvent(c *Check) *Event {
 	log.Debug().Msgf("Creating event '%s' for check '%s'", id, c.Name)
 	return &Event{
 		ID:        id,
+		Labels:    c.Labels.Copy(),
 		CreatedAt: time.Now().UTC(),
 		Check:     c,
 	}
@@ -47,7 +106,7 @@ func (e *Event) Process() {
 		return
 	}
 
-	if e.Check.Status != 0 {
+	if e.Check.Status != StatusOK {
 		incident, err := e.getIncident()
 		if err != nil {
 			log.Error().Msgf("Error getting incident from database: %v", err)
@@ -55,6 +114,14 @@ func (e *Event) Process() {
 
 		if incident == nil { // new incident
 			incident = NewIncident(e.Check)
+			msg := NewMessage(MsgTypeNew, e.Check, incident)
+
+			// the handlers sending the new message are saved with the incident
+			// before it's sent, so they can be unset when sending fails
+			hs := e.accepting(msg)
+			for _, handler := range hs {
+				incident.Notified[handlerKey(handler.Name)] = true
+			}
 
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
@@ -66,14 +133,7 @@ func (e *Event) Process() {
 
 			log.Debug().Msgf("Created new incident '%s'", incident.ID)
 
-			msg := &Message{
-				Body: fmt.Sprintf("%s", incident.Check.Output),
-				Title: fmt.Sprintf("Incident '%s' started - Check '%s' failed after %d attempts",
-					incident.ID, incident.Check.Name, incident.Check.Attempts),
-				Type: MsgTypeNew,
-			}
-
-			e.handle(msg)
+			e.dispatch(hs, msg)
 		} else { // existing incident
 			incident.Update(e.Check.Output)
 			log.Debug().Msgf("Existing incident '%s' found", incident.ID)
@@ -88,17 +148,13 @@ func (e *Event) Process() {
 		}
 
 		if incident.Check.Attempts >= incident.Check.MaxAttempts {
-			if e.Check.Renotify && incident.Check.PreviousOutput != e.Check.Output {
+			if incident.Silenced() {
+				log.Debug().Msgf("Incident '%s' is acknowledged, silenced or resolved, not renotifying", incident.ID)
+			} else if e.Check.Renotify && incident.Check.PreviousOutput != e.Check.Output {
 				log.Debug().Msgf("Check '%s' failed with a different output: previous: '%v' current: '%v'",
 					incident.Check.Name, incident.Check.PreviousOutput, e.Check.Output)
 
-				msg := &Message{
-					Body: fmt.Sprintf("%s", incident.Check.Output),
-					Title: fmt.Sprintf("Incident '%s' updated - Check '%s' failed with a different output",
-						incident.ID, incident.Check.Name),
-					Type: MsgTypeNew,
-				}
-				e.handle(msg)
+				e.handle(NewMessage(MsgTypeUpdate, e.Check, incident))
 			}
 		}
 	} else {
@@ -109,12 +165,7 @@ func (e *Event) Process() {
 
 		if incident != nil {
 			if incident.Check.Attempts >= incident.Check.MaxAttempts {
-				msg := &Message{
-					Body:  fmt.Sprintf("%s", e.Check.Output),
-					Title: fmt.Sprintf("Incident '%s' resolved - Check '%s' passed", incident.ID, incident.Name),
-					Type:  MsgTypeResolve,
-				}
-				e.handle(msg)
+				e.handle(NewMessage(MsgTypeResolve, e.Check, incident))
 			}
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
@@ -147,22 +198,45 @@ func (e *Event) getIncident() (*Incident, error) {
 	return incident, nil
 }
 
+// handle queues a message to be sent by every handler of the check accepting it
 func (e *Event) handle(msg *Message) {
-	for _, handler := range e.Check.Handlers {
-		if e.Check.Status == 0 || e.Check.Status == 2 {
-			err := handler.Handler.Send(msg)
-			if err != nil {
-				log.Error().Msgf("Error sending message: %v", err)
-			}
+	e.dispatch(e.accepting(msg), msg)
+}
+
+// accepting returns the handlers of the check accepting a message. The resolve
+// messages are only sent by the handlers that sent a message of the incident.
+func (e *Event) accepting(msg *Message) []*Handler {
+	var hs []*Handler
+	for _, handler := range e.Check.GetHandlers() {
+		if msg.Type == MsgTypeResolve && !msg.Incident.notified(handler.Name) {
+			log.Debug().Msgf("Handler '%s' didn't send a message of incident '%s', skipping '%s' message",
+				handler.Name, msg.IncidentID, msg.Type)
+			continue
+		}
+		if !handler.accepts(msg) {
+			log.Debug().Msgf("Handler '%s' filtered out '%s' message of check '%s'",
+				handler.Name, msg.Type, e.Check.Name)
+			continue
 		}
+		hs = append(hs, handler)
+	}
+	return hs
+}
+
+// dispatch queues a message to be sent by handlers
+func (e *Event) dispatch(hs []*Handler, msg *Message) {
+	dispatcher := viper.Get("dispatcher").(*Dispatcher)
+	for _, handler := range hs {
+		_ = dispatcher.Dispatch(handler, msg)
 	}
 }
 
 func (e *Event) getHandlers() []*Handler {
 	var handlers []*Handler
 
-	if len(e.Check.Handlers) > 0 {
-		for _, handler := range e.Check.Handlers {
+	checkHandlers := e.Check.GetHandlers()
+	if len(checkHandlers) > 0 {
+		for _, handler := range checkHandlers {
 			handlers = append(handlers, handler)
 		}
 		return handlers
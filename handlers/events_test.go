@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func newEventCheck(name string, handlers ...*Handler) *Check {
+	c := NewCheck()
+	c.Name = name
+	c.MaxAttempts = 2
+	c.Renotify = true
+	c.SetHandlers(handlers, nil)
+	return c
+}
+
+func processEvent(c *Check, status int, output string) {
+	c.Status = status
+	c.Output = output
+	NewEvent(c).Process()
+}
+
+// queueStats returns the queue metrics of a handler of the shared dispatcher
+func queueStats(name string) *QueueStats {
+	for _, s := range viper.Get("dispatcher").(*Dispatcher).Stats() {
+		if s.Handler == name {
+			return &s
+		}
+	}
+	return nil
+}
+
+func TestEventFollowUpsOnlyToNotifiedHandlers(t *testing.T) {
+	store.reset()
+
+	pager := newRecordSender(nil)
+	chat := newRecordSender(nil)
+	mail := newRecordSender(nil)
+	c := newEventCheck("followups",
+		&Handler{Name: "pager.ops", Type: "test", Handler: pager},
+		&Handler{Name: "chat-warnings", Type: "test", Handler: chat, Filter: &Filter{Severities: []string{"WARNING"}}},
+		&Handler{Name: "mail", Type: "test", Handler: mail, Filter: &Filter{Types: []string{MsgTypeNew, MsgTypeUpdate}}},
+	)
+
+	processEvent(c, StatusCritical, "disk 95%")
+	pager.wait(t, 1)
+	mail.wait(t, 1)
+
+	incident := store.incident(t, "followups")
+	if incident == nil || len(incident.Notified) != 2 || !incident.Notified["pager%2Eops"] || !incident.Notified["mail"] {
+		t.Fatalf("unexpected notified handlers %+v", incident)
+	}
+
+	processEvent(c, StatusCritical, "disk 97%")
+	processEvent(c, StatusOK, "disk 50%")
+
+	types := func(msgs []*Message) []string {
+		var ts []string
+		for _, msg := range msgs {
+			ts = append(ts, msg.Type)
+		}
+		return ts
+	}
+
+	if ts := types(pager.wait(t, 2)); len(ts) != 2 || ts[0] != MsgTypeUpdate || ts[1] != MsgTypeResolve {
+		t.Errorf("expected update and resolve, got %v", ts)
+	}
+	if ts := types(mail.wait(t, 1)); ts[0] != MsgTypeUpdate {
+		t.Errorf("expected update, got %v", ts)
+	}
+
+	select {
+	case msg := <-mail.sent:
+		t.Errorf("unexpected '%s' message sent by a handler filtering it out", msg.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if s := queueStats("chat-warnings"); s != nil {
+		t.Errorf("handler filtering out the new message was sent follow ups: %+v", s)
+	}
+
+	if store.incident(t, "followups") != nil {
+		t.Error("resolved incident not deleted")
+	}
+}
+
+func TestEventFollowUpsSkippedAfterFailure(t *testing.T) {
+	store.reset()
+
+	failing := newRecordSender(func(msg *Message) error { return errors.New("unavailable") })
+	h := &Handler{Name: "failing-new", Type: "test", Handler: failing, Retry: &RetryPolicy{Attempts: 1}}
+	c := newEventCheck("failure", h)
+
+	var failed uint64
+	if s := queueStats("failing-new"); s != nil {
+		failed = s.Failed
+	}
+
+	processEvent(c, StatusCritical, "disk 95%")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		incident := store.incident(t, "failure")
+		s := queueStats("failing-new")
+		if v, ok := incident.Notified["failing-new"]; ok && !v && s != nil && s.Failed == failed+1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("handler still notified on incident %+v", incident.Notified)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// the update opens the incident for the handler again, and the resolve
+	// queued behind it is dropped when it fails too
+	processEvent(c, StatusCritical, "disk 97%")
+	processEvent(c, StatusOK, "disk 50%")
+
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		s := queueStats("failing-new")
+		if s != nil && s.Failed == failed+2 && s.Pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the new and update messages to fail, got %+v", s)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if s := queueStats("failing-new"); s.Failed != failed+2 {
+		t.Errorf("resolve message sent after the update failed: %+v", s)
+	}
+}
+
+func TestEventEscalationNotifiesHandler(t *testing.T) {
+	store.reset()
+
+	unfiltered := newRecordSender(nil)
+	c := newEventCheck("escalation", &Handler{Name: "unfiltered", Type: "test", Handler: unfiltered})
+
+	// the new message of a WARNING incident is filtered out without a severities filter
+	processEvent(c, StatusWarning, "disk 85%")
+	incident := store.incident(t, "escalation")
+	if incident == nil || incident.Notified["unfiltered"] {
+		t.Fatalf("unexpected notified handlers %+v", incident)
+	}
+
+	processEvent(c, StatusCritical, "disk 95%")
+	processEvent(c, StatusOK, "disk 50%")
+
+	msgs := unfiltered.wait(t, 2)
+	if msgs[0].Type != MsgTypeUpdate || msgs[0].Severity != "CRITICAL" || msgs[1].Type != MsgTypeResolve {
+		t.Errorf("expected the CRITICAL update and the resolve, got %s and %s", msgs[0].Type, msgs[1].Type)
+	}
+}
+
+func TestHandlerAcceptsResolve(t *testing.T) {
+	window, err := NewTimeWindow("00:00", "00:01", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		filter  *Filter
+		accepts bool
+	}{
+		{"no filter", nil, true},
+		{"severities", &Filter{Severities: []string{"CRITICAL"}}, true},
+		{"window", &Filter{Window: window}, true},
+		{"types", &Filter{Types: []string{MsgTypeResolve}}, true},
+		{"other types", &Filter{Types: []string{MsgTypeNew, MsgTypeUpdate}}, false},
+	}
+
+	msg := &Message{Type: MsgTypeResolve, Severity: "OK", Status: StatusOK}
+	for _, tt := range tests {
+		h := &Handler{Name: "test", Filter: tt.filter}
+		if accepts := h.accepts(msg); accepts != tt.accepts {
+			t.Errorf("%s: expected %v got %v", tt.name, tt.accepts, accepts)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultFileMaxSize    = 100 * 1024 * 1024
+	defaultFileMaxBackups = 5
+)
+
+// fileHandler represents an append-only JSON lines file handler
+type fileHandler struct {
+	*Handler
+	Path       string `json:"path"`
+	MaxSize    int64  `json:"max_size" bson:"max_size"`
+	MaxBackups int    `json:"max_backups" bson:"max_backups"`
+}
+
+var (
+	fileLocksMu sync.Mutex
+	fileLocks   = make(map[string]*sync.Mutex)
+)
+
+// fileLock returns the lock of a path, shared by every handler writing to it
+// so the ones replaced by a reload can't interleave writes with the new ones
+func fileLock(path string) *sync.Mutex {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	fileLocksMu.Lock()
+	defer fileLocksMu.Unlock()
+
+	mu, ok := fileLocks[path]
+	if !ok {
+		mu = &sync.Mutex{}
+		fileLocks[path] = mu
+	}
+	return mu
+}
+
+// FileConfig holds the configuration of a file handler
+type FileConfig struct {
+	Path       string `mapstructure:"path" validate:"required"`
+	MaxSize    int64  `mapstructure:"maxSize"`
+	MaxBackups int    `mapstructure:"maxBackups"`
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "file",
+		Config: func() interface{} { return &FileConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewFileHandler(config.(*FileConfig)), nil
+		},
+	})
+}
+
+// NewFileHandler creates a fileHandler instance
+func NewFileHandler(config *FileConfig) *Handler {
+	f := &fileHandler{
+		Path:       config.Path,
+		MaxSize:    config.MaxSize,
+		MaxBackups: config.MaxBackups,
+	}
+
+	if f.MaxSize <= 0 {
+		f.MaxSize = defaultFileMaxSize
+	}
+
+	if f.MaxBackups <= 0 {
+		f.MaxBackups = defaultFileMaxBackups
+	}
+
+	return &Handler{
+		Type:    "file",
+		Handler: f,
+	}
+}
+
+// fileRecord is a line of the file
+type fileRecord struct {
+	Time time.Time `json:"time"`
+	*Message
+}
+
+// Send appends the message as a JSON line to the file,
+// rotating it first if it would grow over its maximum size
+func (f *fileHandler) Send(msg *Message) error {
+	b, err := json.Marshal(&fileRecord{Time: time.Now().UTC(), Message: msg})
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	mu := fileLock(f.Path)
+	mu.Lock()
+	defer mu.Unlock()
+
+	info, err := os.Stat(f.Path)
+	if err == nil && info.Size() > 0 && info.Size()+int64(len(b)) > f.MaxSize {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(b); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Message successfully written to '%s'", f.Path)
+	return nil
+}
+
+// rotate renames the file to path.1, shifting the existing backups
+// and removing the ones over the maximum number of backups
+func (f *fileHandler) rotate() error {
+	err := os.Remove(fmt.Sprintf("%s.%d", f.Path, f.MaxBackups))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for i := f.MaxBackups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", f.Path, i), fmt.Sprintf("%s.%d", f.Path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	log.Debug().Msgf("Rotating '%s'", f.Path)
+	return os.Rename(f.Path, f.Path+".1")
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFileHandlerSharedPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	// Two instances of the same handler, like before and after a reload
+	old := NewFileHandler(&FileConfig{Path: path})
+	cur := NewFileHandler(&FileConfig{Path: path})
+
+	body := strings.Repeat("x", 8192)
+	var wg sync.WaitGroup
+	for _, h := range []*Handler{old, cur} {
+		wg.Add(1)
+		go func(h *Handler) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if err := h.Handler.Send(&Message{Type: MsgTypeNew, Title: "title", Body: body}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(h)
+	}
+	wg.Wait()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %d is not valid JSON: %v", lines+1, err)
+		}
+		lines++
+	}
+	if lines != 100 {
+		t.Errorf("expected 100 lines, got %d", lines)
+	}
+}
+
+func TestFileHandlerRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	h := NewFileHandler(&FileConfig{Path: path, MaxSize: 200, MaxBackups: 2})
+
+	for i := 0; i < 5; i++ {
+		if err := h.Handler.Send(&Message{Type: MsgTypeNew, Title: strings.Repeat("x", 100)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expected '%s' to exist: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups")
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Filter selects the messages a handler sends, every set condition having to match
+type Filter struct {
+	Severities []string    `json:"severities,omitempty"`
+	Types      []string    `json:"types,omitempty"`
+	Labels     Labels      `json:"labels,omitempty"`
+	Window     *TimeWindow `json:"window,omitempty"`
+}
+
+// TimeWindow is a daily time range in a time zone, which ends
+// the next day when its end is before its start
+type TimeWindow struct {
+	Start    time.Duration  `json:"start"`
+	End      time.Duration  `json:"end"`
+	Location *time.Location `json:"-"`
+}
+
+// NewTimeWindow creates a TimeWindow from start and end times formatted
+// as 'HH:MM' and the name of a time zone, UTC if empty
+func NewTimeWindow(start, end, timezone string) (*TimeWindow, error) {
+	s, err := parseTimeOfDay(start)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := parseTimeOfDay(end)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == e {
+		return nil, errors.New(fmt.Sprintf("start and end '%s' are equal, the window would never match", start))
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unknown time zone '%s'", timezone))
+	}
+
+	return &TimeWindow{Start: s, End: e, Location: loc}, nil
+}
+
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("invalid time '%s', expected HH:MM", s))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains returns whether a time is in the window
+func (w *TimeWindow) Contains(t time.Time) bool {
+	t = t.In(w.Location)
+	d := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+
+	if w.Start <= w.End {
+		return d >= w.Start && d < w.End
+	}
+	return d >= w.Start || d < w.End
+}
+
+// Match returns whether a message passes the filter at the given time
+func (f *Filter) Match(msg *Message, now time.Time) bool {
+	if len(f.Severities) > 0 && !stringInSlice(msg.Severity, f.Severities) {
+		return false
+	}
+
+	if len(f.Types) > 0 && !stringInSlice(msg.Type, f.Types) {
+		return false
+	}
+
+	if len(f.Labels) > 0 && !msg.Labels.Matches(f.Labels) {
+		return false
+	}
+
+	if f.Window != nil && !f.Window.Contains(now) {
+		return false
+	}
+
+	return true
+}
+
+// accepts returns whether a handler sends a message. Without a severity
+// filter, only OK and CRITICAL messages are sent. Resolve messages are
+// only filtered by type, as they close what the new message opened.
+func (h *Handler) accepts(msg *Message) bool {
+	if msg.Type == MsgTypeResolve {
+		return h.Filter == nil || len(h.Filter.Types) == 0 || stringInSlice(msg.Type, h.Filter.Types)
+	}
+
+	if h.Filter == nil || len(h.Filter.Severities) == 0 {
+		if msg.Status != StatusOK && msg.Status != StatusCritical {
+			return false
+		}
+	}
+
+	if h.Filter == nil {
+		return true
+	}
+
+	return h.Filter.Match(msg, time.Now())
+}
+
+func stringInSlice(s string, slice []string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTimeWindow(t *testing.T) {
+	tests := []struct {
+		start, end, timezone string
+		err                  string
+	}{
+		{"09:00", "17:00", "", ""},
+		{"22:00", "06:00", "America/Toronto", ""},
+		{"9h", "17:00", "", "invalid time '9h'"},
+		{"09:00", "24:00", "", "invalid time '24:00'"},
+		{"09:00", "17:00", "Mars/Olympus", "unknown time zone"},
+		{"09:00", "09:00", "", "are equal"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewTimeWindow(tt.start, tt.end, tt.timezone)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s-%s: unexpected error: %v", tt.start, tt.end, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s-%s: expected error '%s', got %v", tt.start, tt.end, tt.err, err)
+		}
+	}
+}
+
+func TestTimeWindowContains(t *testing.T) {
+	day := func(hour, min int) time.Time {
+		return time.Date(2021, 6, 1, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		start, end, timezone string
+		t                    time.Time
+		contains             bool
+	}{
+		{"09:00", "17:00", "", day(9, 0), true},
+		{"09:00", "17:00", "", day(16, 59), true},
+		{"09:00", "17:00", "", day(17, 0), false},
+		{"09:00", "17:00", "", day(8, 59), false},
+		{"22:00", "06:00", "", day(23, 0), true},
+		{"22:00", "06:00", "", day(5, 59), true},
+		{"22:00", "06:00", "", day(12, 0), false},
+		{"09:00", "17:00", "America/Toronto", day(14, 0), true},
+		{"09:00", "17:00", "America/Toronto", day(22, 0), false},
+	}
+
+	for _, tt := range tests {
+		w, err := NewTimeWindow(tt.start, tt.end, tt.timezone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contains := w.Contains(tt.t); contains != tt.contains {
+			t.Errorf("%s-%s %s: expected %v for %s", tt.start, tt.end, tt.timezone, tt.contains, tt.t)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	window, err := NewTimeWindow("09:00", "17:00", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	msg := &Message{Type: MsgTypeNew, Severity: "WARNING", Labels: Labels{"team": "ops", "env": "prod"}}
+
+	tests := []struct {
+		name   string
+		filter *Filter
+		match  bool
+	}{
+		{"empty", &Filter{}, true},
+		{"severity", &Filter{Severities: []string{"WARNING", "CRITICAL"}}, true},
+		{"other severity", &Filter{Severities: []string{"CRITICAL"}}, false},
+		{"type", &Filter{Types: []string{MsgTypeNew}}, true},
+		{"other type", &Filter{Types: []string{MsgTypeResolve}}, false},
+		{"labels", &Filter{Labels: Labels{"team": "ops"}}, true},
+		{"other labels", &Filter{Labels: Labels{"team": "dev"}}, false},
+		{"window", &Filter{Window: window}, true},
+		{"all", &Filter{Severities: []string{"WARNING"}, Labels: Labels{"env": "prod"}, Window: window}, true},
+	}
+
+	for _, tt := range tests {
+		if match := tt.filter.Match(msg, now); match != tt.match {
+			t.Errorf("%s: expected %v got %v", tt.name, tt.match, match)
+		}
+	}
+}
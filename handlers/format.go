@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// defaultEmailTemplate is the HTML body of the emails
+const defaultEmailTemplate = `<html>
+<body style="font-family: sans-serif;">
+<h3 style="border-left: 6px solid {{.Color}}; padding-left: 8px;">{{.Message.Title}}</h3>
+{{- if .Facts}}
+<table cellpadding="4">
+{{- range .Facts}}
+<tr><th align="left">{{.Name}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+{{- range .Labels}}
+<tr><th align="left">{{.Name}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<pre>{{.Message.Body}}</pre>
+</body>
+</html>
+`
+
+var emailTemplate = htmltemplate.Must(htmltemplate.New("email").Parse(defaultEmailTemplate))
+
+const (
+	colorNew     = "#DF0101"
+	colorUpdate  = "#FF8000"
+	colorResolve = "#33FF33"
+)
+
+// messageColor returns the color of a message type as a hex string
+func messageColor(msgType string) string {
+	switch msgType {
+	case MsgTypeNew:
+		return colorNew
+	case MsgTypeUpdate:
+		return colorUpdate
+	case MsgTypeResolve:
+		return colorResolve
+	default:
+		return ""
+	}
+}
+
+// messageColorInt returns the color of a message type as an integer
+func messageColorInt(msgType string) int {
+	color := messageColor(msgType)
+	if color == "" {
+		return 0
+	}
+
+	i, _ := strconv.ParseInt(color[1:], 16, 32)
+	return int(i)
+}
+
+// fact is a name and value pair shown by the handlers supporting structured fields
+type fact struct {
+	Name  string
+	Value string
+}
+
+// messageFacts returns the check and incident details of a message as structured fields
+func messageFacts(msg *Message) []fact {
+	if msg.CheckName == "" {
+		return nil
+	}
+
+	facts := []fact{
+		{"Check", msg.CheckName},
+		{"Status", msg.Severity},
+	}
+
+	if msg.PreviousStatus != msg.Status {
+		facts = append(facts, fact{"Previous status", StatusName(msg.PreviousStatus)})
+	}
+
+	facts = append(facts,
+		fact{"Duration", fmt.Sprintf("%.3fs", msg.Duration)},
+		fact{"Attempts", strconv.Itoa(msg.Attempts)},
+	)
+
+	if msg.IncidentID != "" {
+		facts = append(facts, fact{"Incident", msg.IncidentID})
+	}
+
+	if !msg.StartedAt.IsZero() {
+		facts = append(facts, fact{"Started", msg.StartedAt.Format("2006-01-02 15:04:05 MST")})
+	}
+
+	if msg.RunbookURL != "" {
+		facts = append(facts, fact{"Runbook", msg.RunbookURL})
+	}
+
+	return facts
+}
+
+// labelFacts returns the labels of a message as structured fields sorted by name
+func labelFacts(msg *Message) []fact {
+	keys := make([]string, 0, len(msg.Labels))
+	for k := range msg.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	facts := make([]fact, 0, len(keys))
+	for _, k := range keys {
+		facts = append(facts, fact{k, msg.Labels[k]})
+	}
+
+	return facts
+}
+
+// messageLevel returns the urgency of a message from 1 (lowest) to 5 (highest)
+// based on its type and the status of its check
+func messageLevel(msg *Message) int {
+	if msg.Type == MsgTypeResolve {
+		return 3
+	}
+
+	if msg.CheckName == "" {
+		return 3
+	}
+
+	switch msg.Status {
+	case StatusOK:
+		return 3
+	case StatusWarning:
+		return 4
+	case StatusCritical:
+		if msg.Type == MsgTypeUpdate {
+			return 4
+		}
+		return 5
+	default:
+		return 4
+	}
+}
+
+// emailData is the data given to the HTML email templates
+type emailData struct {
+	Message  *Message
+	Check    *Check
+	Incident *Incident
+	Color    string
+	Facts    []fact
+	Labels   []fact
+}
+
+// renderEmail renders the HTML body of an email with a template
+func renderEmail(t *htmltemplate.Template, msg *Message) (string, error) {
+	data := &emailData{
+		Message:  msg,
+		Check:    msg.Check,
+		Incident: msg.Incident,
+		Color:    messageColor(msg.Type),
+		Facts:    messageFacts(msg),
+		Labels:   labelFacts(msg),
+	}
+
+	var buf bytes.Buffer
+	err := t.Execute(&buf, data)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Error rendering email template: %v", err))
+	}
+
+	return buf.String(), nil
+}
+
+// truncate shortens a string to at most n characters without splitting a multi-byte character
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	runes := []rune(s)
+	return string(runes[:n])
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s        string
+		n        int
+		expected string
+	}{
+		{"", 3, ""},
+		{"abc", 3, "abc"},
+		{"abcdef", 3, "abc"},
+		{"héllo", 2, "hé"},
+		{"日本語のテキスト", 3, "日本語"},
+		{"✅ ok", 1, "✅"},
+	}
+
+	for _, tt := range tests {
+		got := truncate(tt.s, tt.n)
+		if got != tt.expected {
+			t.Errorf("truncate(%q, %d): expected %q got %q", tt.s, tt.n, tt.expected, got)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d) returned invalid UTF-8", tt.s, tt.n)
+		}
+	}
+}
+
+func TestMessageColor(t *testing.T) {
+	tests := []struct {
+		msgType string
+		color   string
+		value   int
+	}{
+		{MsgTypeNew, colorNew, 0xDF0101},
+		{MsgTypeUpdate, colorUpdate, 0xFF8000},
+		{MsgTypeResolve, colorResolve, 0x33FF33},
+		{"other", "", 0},
+	}
+
+	for _, tt := range tests {
+		if c := messageColor(tt.msgType); c != tt.color {
+			t.Errorf("messageColor(%q): expected %q got %q", tt.msgType, tt.color, c)
+		}
+		if v := messageColorInt(tt.msgType); v != tt.value {
+			t.Errorf("messageColorInt(%q): expected %d got %d", tt.msgType, tt.value, v)
+		}
+	}
+}
+
+func TestMessageFacts(t *testing.T) {
+	started := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		msg      *Message
+		expected []fact
+	}{
+		{"manual", &Message{Title: "test"}, nil},
+		{
+			name: "same status",
+			msg: &Message{CheckName: "disk", Severity: "CRITICAL", Status: StatusCritical,
+				PreviousStatus: StatusCritical, Duration: 0.25, Attempts: 3},
+			expected: []fact{
+				{"Check", "disk"}, {"Status", "CRITICAL"}, {"Duration", "0.250s"}, {"Attempts", "3"},
+			},
+		},
+		{
+			name: "incident",
+			msg: &Message{CheckName: "disk", Severity: "CRITICAL", Status: StatusCritical, Duration: 1, Attempts: 1,
+				IncidentID: "abc", StartedAt: started, RunbookURL: "https://example.com/runbooks/disk"},
+			expected: []fact{
+				{"Check", "disk"}, {"Status", "CRITICAL"}, {"Previous status", "OK"}, {"Duration", "1.000s"},
+				{"Attempts", "1"}, {"Incident", "abc"}, {"Started", "2021-03-04 05:06:07 UTC"},
+				{"Runbook", "https://example.com/runbooks/disk"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		if facts := messageFacts(tt.msg); !reflect.DeepEqual(facts, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, facts)
+		}
+	}
+}
+
+func TestLabelFacts(t *testing.T) {
+	msg := &Message{Labels: Labels{"team": "ops", "env": "prod"}}
+	expected := []fact{{"env", "prod"}, {"team", "ops"}}
+	if facts := labelFacts(msg); !reflect.DeepEqual(facts, expected) {
+		t.Errorf("expected %v, got %v", expected, facts)
+	}
+
+	if facts := labelFacts(&Message{}); len(facts) != 0 {
+		t.Errorf("expected no facts, got %v", facts)
+	}
+}
+
+func TestNewMessageFields(t *testing.T) {
+	c := NewCheck()
+	c.Name = "disk"
+	c.Status = StatusCritical
+	c.History = []int{StatusCritical, StatusWarning}
+	c.Labels = Labels{"team": "ops"}
+	c.RunbookURL = "https://example.com/runbooks/disk"
+	incident := NewIncident(c)
+
+	msg := NewMessage(MsgTypeNew, c, incident)
+	if msg.CheckName != "disk" || msg.IncidentID != incident.ID || msg.Severity != "CRITICAL" ||
+		msg.PreviousStatus != StatusWarning || msg.RunbookURL != c.RunbookURL || msg.Labels["team"] != "ops" ||
+		!msg.StartedAt.Equal(incident.CreatedAt) {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestRenderEmail(t *testing.T) {
+	msg := &Message{
+		Type:      MsgTypeResolve,
+		Title:     "disk <passed>",
+		Body:      "output",
+		CheckName: "disk",
+		Severity:  "OK",
+		Labels:    Labels{"team": "ops"},
+	}
+
+	html, err := renderEmail(emailTemplate, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []string{colorResolve, "disk &lt;passed&gt;", "<th align=\"left\">Check</th><td>disk</td>",
+		"<th align=\"left\">team</th><td>ops</td>", "<pre>output</pre>"} {
+		if !strings.Contains(html, e) {
+			t.Errorf("email doesn't contain %q:\n%s", e, html)
+		}
+	}
+}
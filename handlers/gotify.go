@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// gotifyPriorities maps message levels to Gotify priorities
+var gotifyPriorities = map[int]int{1: 1, 2: 2, 3: 4, 4: 6, 5: 8}
+
+// gotifyHandler represents a Gotify push notification handler
+type gotifyHandler struct {
+	*Handler
+	URL    string `json:"url"`
+	Token  string `json:"-" bson:"-"`
+	client *http.Client
+}
+
+// GotifyConfig holds the configuration of a Gotify handler
+type GotifyConfig struct {
+	URL     string        `mapstructure:"url" validate:"required"`
+	Token   string        `mapstructure:"token" validate:"required"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "gotify",
+		Config: func() interface{} { return &GotifyConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewGotifyHandler(config.(*GotifyConfig)), nil
+		},
+	})
+}
+
+// NewGotifyHandler creates a gotifyHandler instance
+func NewGotifyHandler(config *GotifyConfig) *Handler {
+	g := &gotifyHandler{
+		URL:    strings.TrimSuffix(config.URL, "/"),
+		Token:  config.Token,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+
+	if g.client.Timeout == 0 {
+		g.client.Timeout = defaultHTTPTimeout
+	}
+
+	return &Handler{
+		Type:    "gotify",
+		Handler: g,
+	}
+}
+
+type gotifyMessage struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+// Send pushes the message to a Gotify server
+func (g *gotifyHandler) Send(msg *Message) error {
+	b, err := json.Marshal(&gotifyMessage{
+		Title:    msg.Title,
+		Message:  msg.Body,
+		Priority: gotifyPriorities[messageLevel(msg)],
+	})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+		"X-Gotify-Key": g.Token,
+	}
+	_, err = doRequest(g.client, http.MethodPost, g.URL+"/message", headers, b)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Message successfully pushed to Gotify '%s'", g.URL)
+	return nil
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGotifyHandlerSend(t *testing.T) {
+	tests := []struct {
+		msg      *Message
+		priority int
+	}{
+		{&Message{Type: MsgTypeNew, Title: "manual"}, 4},
+		{testCheckMessage(MsgTypeNew, "disk failed", "disk", StatusCritical, ""), 8},
+		{testCheckMessage(MsgTypeUpdate, "disk failed", "disk", StatusCritical, ""), 6},
+		{testCheckMessage(MsgTypeResolve, "disk passed", "disk", StatusOK, ""), 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msg.Type, func(t *testing.T) {
+			var path, key string
+			received := &gotifyMessage{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path, key = r.URL.Path, r.Header.Get("X-Gotify-Key")
+				_ = json.NewDecoder(r.Body).Decode(received)
+			}))
+			defer srv.Close()
+
+			h := NewGotifyHandler(&GotifyConfig{URL: srv.URL + "/", Token: "secret"})
+			tt.msg.Body = "output"
+			if err := h.Handler.Send(tt.msg); err != nil {
+				t.Fatal(err)
+			}
+
+			if path != "/message" || key != "secret" {
+				t.Errorf("unexpected request to '%s' with key '%s'", path, key)
+			}
+			if received.Title != tt.msg.Title || received.Message != "output" || received.Priority != tt.priority {
+				t.Errorf("expected priority %d, got %+v", tt.priority, received)
+			}
+		})
+	}
+}
+
+func TestMessageLevel(t *testing.T) {
+	tests := []struct {
+		msg   *Message
+		level int
+	}{
+		{&Message{Type: MsgTypeNew}, 3},
+		{testCheckMessage(MsgTypeResolve, "", "disk", StatusCritical, ""), 3},
+		{testCheckMessage(MsgTypeNew, "", "disk", StatusOK, ""), 3},
+		{testCheckMessage(MsgTypeNew, "", "disk", StatusWarning, ""), 4},
+		{testCheckMessage(MsgTypeNew, "", "disk", StatusCritical, ""), 5},
+		{testCheckMessage(MsgTypeUpdate, "", "disk", StatusCritical, ""), 4},
+		{testCheckMessage(MsgTypeNew, "", "disk", StatusUnknown, ""), 4},
+	}
+
+	for _, tt := range tests {
+		if l := messageLevel(tt.msg); l != tt.level {
+			t.Errorf("%+v: expected level %d, got %d", tt.msg, tt.level, l)
+		}
+	}
+}
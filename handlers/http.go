@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// defaultHTTPTimeout is used by the handlers calling HTTP APIs when no timeout is configured
+const defaultHTTPTimeout = 10 * time.Second
+
+// doRequest sends a request and returns the response body,
+// non-2xx responses are returned as errors
+func doRequest(client *http.Client, method, url string, headers map[string]string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return b, errors.New(fmt.Sprintf("%s %s returned status code '%d' body: '%s'",
+			method, url, resp.StatusCode, bytes.TrimSpace(b)))
+	}
+
+	return b, nil
+}
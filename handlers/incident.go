@@ -1,28 +1,47 @@
 package handlers
 
 import (
+	"context"
+	"strings"
 	"time"
 
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/uberwachen/storage"
 	"github.com/alexferl/uberwachen/util"
 )
 
 type Incident struct {
 	*Check
-	ID            string    `json:"id" bson:"_id"`
-	Message       *Message  `json:"-" bson:"-"`
-	Name          string    `json:"name"`
-	CreatedAt     time.Time `json:"created_at" bson:"created_at"`
-	LastUpdatedAt time.Time `json:"last_updated_at" bson:"last_updated_at"`
+	ID            string                   `json:"id" bson:"_id"`
+	Message       *Message                 `json:"-" bson:"-"`
+	Name          string                   `json:"name"`
+	Labels        Labels                   `json:"labels"`
+	CreatedAt     time.Time                `json:"created_at" bson:"created_at"`
+	LastUpdatedAt time.Time                `json:"last_updated_at" bson:"last_updated_at"`
+	SlackMessages map[string]*SlackMessage `json:"slack_messages,omitempty" bson:"slack_messages,omitempty"`
+	AckedBy       string                   `json:"acked_by,omitempty" bson:"acked_by,omitempty"`
+	AckedAt       time.Time                `json:"acked_at,omitempty" bson:"acked_at,omitempty"`
+	SilencedBy    string                   `json:"silenced_by,omitempty" bson:"silenced_by,omitempty"`
+	SilencedUntil time.Time                `json:"silenced_until,omitempty" bson:"silenced_until,omitempty"`
+	ResolvedBy    string                   `json:"resolved_by,omitempty" bson:"resolved_by,omitempty"`
+	ResolvedAt    time.Time                `json:"resolved_at,omitempty" bson:"resolved_at,omitempty"`
+	Notified      map[string]bool          `json:"notified,omitempty" bson:"notified"`
+	Deliveries    []*Delivery              `json:"deliveries" bson:"-"`
 }
 
 func NewIncident(c *Check) *Incident {
 	id := util.GenerateShortId()
+	c.mu.Lock()
 	c.Attempts = 1
+	c.mu.Unlock()
 	return &Incident{
 		ID:        id,
 		CreatedAt: time.Now().UTC(),
 		Check:     c,
 		Name:      c.Name,
+		Labels:    c.Labels.Copy(),
+		Notified:  make(map[string]bool),
 	}
 }
 
@@ -31,3 +50,65 @@ func (i *Incident) Update(output string) {
 	i.Check.Attempts += 1
 	i.LastUpdatedAt = time.Now().UTC()
 }
+
+// Acknowledge marks the incident as acknowledged by a user
+func (i *Incident) Acknowledge(user string) error {
+	i.AckedBy = user
+	i.AckedAt = time.Now().UTC()
+	return i.setFields(map[string]interface{}{"acked_by": i.AckedBy, "acked_at": i.AckedAt})
+}
+
+// Silence stops the notifications of updates to the incident for a duration
+func (i *Incident) Silence(user string, d time.Duration) error {
+	i.SilencedBy = user
+	i.SilencedUntil = time.Now().UTC().Add(d)
+	return i.setFields(map[string]interface{}{"silenced_by": i.SilencedBy, "silenced_until": i.SilencedUntil})
+}
+
+// Silenced returns whether updates to the incident should not be notified
+func (i *Incident) Silenced() bool {
+	return i.AckedBy != "" || i.ResolvedBy != "" || time.Now().Before(i.SilencedUntil)
+}
+
+// Resolve marks the incident as resolved by a user before its check passes. The incident
+// is kept, its updates not being notified, so it isn't opened again while the check fails.
+func (i *Incident) Resolve(user string) error {
+	i.ResolvedBy = user
+	i.ResolvedAt = time.Now().UTC()
+	return i.setFields(map[string]interface{}{"resolved_by": i.ResolvedBy, "resolved_at": i.ResolvedAt})
+}
+
+// notified returns whether a handler sent a message of the incident. The
+// incidents saved before it was recorded count as notified by every handler.
+func (i *Incident) notified(handler string) bool {
+	if i.Notified == nil {
+		return true
+	}
+	return i.Notified[handlerKey(handler)]
+}
+
+// setNotified saves whether a handler sent a message of the incident
+func (i *Incident) setNotified(handler string, notified bool) error {
+	key := handlerKey(handler)
+	if i.Notified == nil {
+		i.Notified = make(map[string]bool)
+	}
+	i.Notified[key] = notified
+	return i.setFields(map[string]interface{}{"notified." + key: notified})
+}
+
+// handlerKey returns the key of a handler in the maps of an incident,
+// escaping the dots MongoDB would take for nested fields
+func handlerKey(name string) string {
+	return strings.NewReplacer("%", "%25", ".", "%2E", "$", "%24").Replace(name)
+}
+
+// setFields saves the given fields of the incident to the database
+func (i *Incident) setFields(fields map[string]interface{}) error {
+	db := viper.Get("storage").(storage.Storage)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return db.UpdateFields(ctx, i.ID, fields)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import "testing"
+
+func TestHandlerKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"slack", "slack"},
+		{"slack.ops", "slack%2Eops"},
+		{"$slack", "%24slack"},
+		{"100%.ops", "100%25%2Eops"},
+	}
+
+	for _, tt := range tests {
+		if key := handlerKey(tt.name); key != tt.key {
+			t.Errorf("handlerKey(%q): expected %q got %q", tt.name, tt.key, key)
+		}
+	}
+}
+
+func TestIncidentNotified(t *testing.T) {
+	legacy := &Incident{}
+	if !legacy.notified("slack") {
+		t.Error("incidents without notified handlers should count as notified by all")
+	}
+
+	c := NewCheck()
+	incident := NewIncident(c)
+	incident.Notified[handlerKey("slack.ops")] = true
+	if !incident.notified("slack.ops") || incident.notified("mail") {
+		t.Errorf("unexpected notified handlers %v", incident.Notified)
+	}
+}
@@ -7,7 +7,11 @@ type HandlerSender interface {
 
 // Handler represents a notification handler
 type Handler struct {
-	Name    string        `json:"name"`
-	Type    string        `json:"type"`
-	Handler HandlerSender `json:"handler,omitempty" bson:"-"`
+	Name      string        `json:"name"`
+	Type      string        `json:"type"`
+	Route     Labels        `json:"route,omitempty" bson:"-"`
+	Retry     *RetryPolicy  `json:"retry,omitempty" bson:"-"`
+	Templates Templates     `json:"-" bson:"-"`
+	Filter    *Filter       `json:"filters,omitempty" bson:"-"`
+	Handler   HandlerSender `json:"handler,omitempty" bson:"-"`
 }
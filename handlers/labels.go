@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Labels holds arbitrary key/value metadata attached to a check
+type Labels map[string]string
+
+// ParseLabelSelector parses a selector of the form 'key=value,key2=value2'
+func ParseLabelSelector(selector string) (Labels, error) {
+	labels := Labels{}
+	if strings.TrimSpace(selector) == "" {
+		return labels, nil
+	}
+
+	for _, pair := range strings.Split(selector, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.New(fmt.Sprintf("invalid label selector '%s', expected 'key=value'", pair))
+		}
+
+		key := strings.TrimSpace(kv[0])
+		if key == "" {
+			return nil, errors.New(fmt.Sprintf("invalid label selector '%s', key cannot be empty", pair))
+		}
+
+		labels[key] = strings.TrimSpace(kv[1])
+	}
+
+	return labels, nil
+}
+
+// Matches returns true if every label in selector is present with the same value
+func (l Labels) Matches(selector Labels) bool {
+	for k, v := range selector {
+		if val, ok := l[k]; !ok || val != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Copy returns a copy of the labels
+func (l Labels) Copy() Labels {
+	if l == nil {
+		return nil
+	}
+
+	labels := make(Labels, len(l))
+	for k, v := range l {
+		labels[k] = v
+	}
+	return labels
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		labels   Labels
+		err      string
+	}{
+		{"", Labels{}, ""},
+		{"  ", Labels{}, ""},
+		{"team=ops", Labels{"team": "ops"}, ""},
+		{"team = ops, env=prod", Labels{"team": "ops", "env": "prod"}, ""},
+		{"url=http://example.com/?a=b", Labels{"url": "http://example.com/?a=b"}, ""},
+		{"team=", Labels{"team": ""}, ""},
+		{"team", nil, "expected 'key=value'"},
+		{"=ops", nil, "key cannot be empty"},
+		{"team=ops,", nil, "expected 'key=value'"},
+	}
+
+	for _, tt := range tests {
+		labels, err := ParseLabelSelector(tt.selector)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: expected error '%s', got %v", tt.selector, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.selector, err)
+			continue
+		}
+		if !reflect.DeepEqual(labels, tt.labels) {
+			t.Errorf("%q: expected %v got %v", tt.selector, tt.labels, labels)
+		}
+	}
+}
+
+func TestLabelsMatches(t *testing.T) {
+	labels := Labels{"team": "ops", "env": "prod"}
+
+	tests := []struct {
+		selector Labels
+		matches  bool
+	}{
+		{nil, true},
+		{Labels{}, true},
+		{Labels{"team": "ops"}, true},
+		{Labels{"team": "ops", "env": "prod"}, true},
+		{Labels{"team": "dev"}, false},
+		{Labels{"team": "ops", "region": "east"}, false},
+		{Labels{"env": ""}, false},
+	}
+
+	for _, tt := range tests {
+		if matches := labels.Matches(tt.selector); matches != tt.matches {
+			t.Errorf("%v: expected %v got %v", tt.selector, tt.matches, matches)
+		}
+	}
+
+	if !Labels(nil).Matches(Labels{}) || Labels(nil).Matches(Labels{"team": "ops"}) {
+		t.Error("unexpected match of nil labels")
+	}
+}
+
+func TestLabelsCopy(t *testing.T) {
+	if Labels(nil).Copy() != nil {
+		t.Error("copy of nil labels should be nil")
+	}
+
+	labels := Labels{"team": "ops"}
+	c := labels.Copy()
+	c["team"] = "dev"
+	if labels["team"] != "ops" {
+		t.Error("copy shares the labels")
+	}
+}
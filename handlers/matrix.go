@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/uberwachen/util"
+)
+
+// matrixHandler represents a Matrix room handler
+type matrixHandler struct {
+	*Handler
+	HomeserverURL string `json:"homeserver_url" bson:"homeserver_url"`
+	RoomID        string `json:"room_id" bson:"room_id"`
+	AccessToken   string `json:"-" bson:"-"`
+	MsgType       string `json:"msgtype"`
+	client        *http.Client
+}
+
+// MatrixConfig holds the configuration of a Matrix handler
+type MatrixConfig struct {
+	HomeserverURL string        `mapstructure:"homeserverUrl" validate:"required"`
+	RoomID        string        `mapstructure:"roomId" validate:"required"`
+	AccessToken   string        `mapstructure:"accessToken" validate:"required"`
+	MsgType       string        `mapstructure:"msgtype"`
+	Timeout       time.Duration `mapstructure:"timeout"`
+}
+
+// Validate checks the message type is supported
+func (c *MatrixConfig) Validate() error {
+	switch c.MsgType {
+	case "", "m.text", "m.notice":
+		return nil
+	default:
+		return errors.New("key 'msgtype': must be one of 'm.text' or 'm.notice'")
+	}
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "matrix",
+		Config: func() interface{} { return &MatrixConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewMatrixHandler(config.(*MatrixConfig)), nil
+		},
+	})
+}
+
+// NewMatrixHandler creates a matrixHandler instance
+func NewMatrixHandler(config *MatrixConfig) *Handler {
+	m := &matrixHandler{
+		HomeserverURL: strings.TrimSuffix(config.HomeserverURL, "/"),
+		RoomID:        config.RoomID,
+		AccessToken:   config.AccessToken,
+		MsgType:       config.MsgType,
+		client:        &http.Client{Timeout: config.Timeout},
+	}
+
+	if m.MsgType == "" {
+		m.MsgType = "m.text"
+	}
+
+	if m.client.Timeout == 0 {
+		m.client.Timeout = defaultHTTPTimeout
+	}
+
+	return &Handler{
+		Type:    "matrix",
+		Handler: m,
+	}
+}
+
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// Send sends a message to a Matrix room
+func (m *matrixHandler) Send(msg *Message) error {
+	b, err := json.Marshal(&matrixMessage{
+		MsgType: m.MsgType,
+		Body:    fmt.Sprintf("%s \n %s", msg.Title, msg.Body),
+		Format:  "org.matrix.custom.html",
+		FormattedBody: fmt.Sprintf("<strong>%s</strong><br><pre>%s</pre>",
+			html.EscapeString(msg.Title), html.EscapeString(msg.Body)),
+	})
+	if err != nil {
+		return err
+	}
+
+	// The transaction ID is the same for every attempt at sending a message,
+	// so the homeserver ignores a retry of a request it already handled
+	txnID := msg.ID
+	if txnID == "" {
+		txnID = fmt.Sprintf("%d%s", time.Now().UnixNano(), util.GenerateShortId())
+	}
+	u := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		m.HomeserverURL, url.PathEscape(m.RoomID), url.PathEscape(txnID))
+
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "Bearer " + m.AccessToken,
+	}
+	_, err = doRequest(m.client, http.MethodPut, u, headers, b)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Message successfully sent to Matrix room '%s'", m.RoomID)
+	return nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMatrixHandlerRetrySameTransaction(t *testing.T) {
+	var paths []string
+	sent := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Authorization"))
+		}
+		var m matrixMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil || m.MsgType != "m.text" {
+			t.Errorf("unexpected message %+v: %v", m, err)
+		}
+
+		paths = append(paths, r.URL.EscapedPath())
+		if len(paths) == 1 {
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		w.Write([]byte(`{"event_id":"$1"}`))
+		close(sent)
+	}))
+	defer srv.Close()
+
+	h := NewMatrixHandler(&MatrixConfig{HomeserverURL: srv.URL + "/", RoomID: "!room:example.com", AccessToken: "token"})
+	h.Name = "matrix"
+	h.Retry = &RetryPolicy{Attempts: 3, MinDelay: time.Millisecond, MaxDelay: time.Millisecond, Factor: 1}
+
+	c := NewCheck()
+	c.Name = "disk"
+	msg := NewMessage(MsgTypeNew, c, NewIncident(c))
+
+	if err := NewDispatcher(10, 1).Dispatch(h, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the message to be sent")
+	}
+	if len(paths) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(paths))
+	}
+
+	expected := "/_matrix/client/v3/rooms/%21room:example.com/send/m.room.message/" + msg.ID
+	if paths[0] != expected || paths[1] != expected {
+		t.Errorf("expected both attempts to use %s, got %v", expected, paths)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+)
+
+const defaultNtfyURL = "https://ntfy.sh"
+
+// ntfyTags are the emoji tags of each message type
+var ntfyTags = map[string]string{
+	MsgTypeNew:     "rotating_light",
+	MsgTypeUpdate:  "warning",
+	MsgTypeResolve: "white_check_mark",
+}
+
+// ntfyHandler represents an ntfy push notification handler
+type ntfyHandler struct {
+	*Handler
+	URL      string `json:"url"`
+	Topic    string `json:"topic"`
+	Token    string `json:"-" bson:"-"`
+	Username string `json:"-" bson:"-"`
+	Password string `json:"-" bson:"-"`
+	client   *http.Client
+}
+
+// NtfyConfig holds the configuration of an ntfy handler
+type NtfyConfig struct {
+	URL      string        `mapstructure:"url"`
+	Topic    string        `mapstructure:"topic" validate:"required"`
+	Token    string        `mapstructure:"token"`
+	Username string        `mapstructure:"username"`
+	Password string        `mapstructure:"password"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "ntfy",
+		Config: func() interface{} { return &NtfyConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewNtfyHandler(config.(*NtfyConfig)), nil
+		},
+	})
+}
+
+// NewNtfyHandler creates an ntfyHandler instance
+func NewNtfyHandler(config *NtfyConfig) *Handler {
+	n := &ntfyHandler{
+		URL:      strings.TrimSuffix(config.URL, "/"),
+		Topic:    config.Topic,
+		Token:    config.Token,
+		Username: config.Username,
+		Password: config.Password,
+		client:   &http.Client{Timeout: config.Timeout},
+	}
+
+	if n.URL == "" {
+		n.URL = defaultNtfyURL
+	}
+
+	if n.client.Timeout == 0 {
+		n.client.Timeout = defaultHTTPTimeout
+	}
+
+	return &Handler{
+		Type:    "ntfy",
+		Handler: n,
+	}
+}
+
+// Send publishes the message to an ntfy topic
+func (n *ntfyHandler) Send(msg *Message) error {
+	headers := map[string]string{
+		"Title":    headerValue(msg.Title),
+		"Priority": strconv.Itoa(messageLevel(msg)),
+	}
+
+	if tag, ok := ntfyTags[msg.Type]; ok {
+		headers["Tags"] = tag
+	}
+
+	if n.Token != "" {
+		headers["Authorization"] = "Bearer " + n.Token
+	} else if n.Username != "" {
+		auth := base64.StdEncoding.EncodeToString([]byte(n.Username + ":" + n.Password))
+		headers["Authorization"] = "Basic " + auth
+	}
+
+	_, err := doRequest(n.client, http.MethodPost, n.URL+"/"+n.Topic, headers, []byte(msg.Body))
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Message successfully published to ntfy topic '%s'", n.Topic)
+	return nil
+}
+
+// headerValue replaces the control characters of a header value, like the
+// line breaks of a title rendered by a template, which HTTP doesn't allow
+func headerValue(s string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s))
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNtfyHandlerSend(t *testing.T) {
+	var req *http.Request
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		req, body = r, string(b)
+	}))
+	defer srv.Close()
+
+	h := NewNtfyHandler(&NtfyConfig{URL: srv.URL, Topic: "alerts", Token: "secret"})
+
+	msg := &Message{Type: MsgTypeNew, Title: "Check 'disk' failed\r\non host", Body: "output", Status: StatusCritical}
+	if err := h.Handler.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if req.URL.Path != "/alerts" {
+		t.Errorf("unexpected path '%s'", req.URL.Path)
+	}
+	if title := req.Header.Get("Title"); title != "Check 'disk' failed  on host" {
+		t.Errorf("unexpected title '%s'", title)
+	}
+	if tags := req.Header.Get("Tags"); tags != "rotating_light" {
+		t.Errorf("unexpected tags '%s'", tags)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "Bearer secret" {
+		t.Errorf("unexpected authorization '%s'", auth)
+	}
+	if body != "output" {
+		t.Errorf("unexpected body '%s'", body)
+	}
+}
+
+func TestHeaderValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"title", "title"},
+		{"line 1\nline 2", "line 1 line 2"},
+		{"title\r\n", "title"},
+		{"tab\there", "tab here"},
+		{"héllo ✅", "héllo ✅"},
+	}
+
+	for _, tt := range tests {
+		if got := headerValue(tt.value); got != tt.expected {
+			t.Errorf("headerValue(%q): expected %q got %q", tt.value, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/uberwachen/util"
+)
+
+const (
+	defaultOpsgenieURL = "https://api.opsgenie.com"
+
+	// opsgenieMaxMessage is the maximum length of the message of an alert
+	opsgenieMaxMessage = 130
+)
+
+// opsgeniePriorities maps check statuses to Opsgenie priorities
+var opsgeniePriorities = map[string]string{
+	"OK":       "P5",
+	"WARNING":  "P3",
+	"CRITICAL": "P1",
+	"UNKNOWN":  "P3",
+}
+
+// opsgenieHandler represents an Opsgenie alert handler
+type opsgenieHandler struct {
+	*Handler
+	ApiKey     string            `json:"-" bson:"-"`
+	URL        string            `json:"url"`
+	Source     string            `json:"source"`
+	Team       string            `json:"team"`
+	Tags       []string          `json:"tags"`
+	Priorities map[string]string `json:"priorities"`
+	client     *http.Client
+}
+
+// OpsgenieConfig holds the configuration of an Opsgenie handler
+type OpsgenieConfig struct {
+	ApiKey     string            `mapstructure:"apiKey" validate:"required"`
+	URL        string            `mapstructure:"url"`
+	Source     string            `mapstructure:"source"`
+	Team       string            `mapstructure:"team"`
+	Tags       []string          `mapstructure:"tags"`
+	Priorities map[string]string `mapstructure:"priorities"`
+	Timeout    time.Duration     `mapstructure:"timeout"`
+}
+
+// Validate checks the priorities map statuses to Opsgenie priorities
+func (c *OpsgenieConfig) Validate() error {
+	var errs util.Errors
+
+	for status, priority := range c.Priorities {
+		if _, ok := opsgeniePriorities[status]; !ok {
+			errs = append(errs, errors.New(fmt.Sprintf("key 'priorities.%s': unknown status, must be one of "+
+				"'OK', 'WARNING', 'CRITICAL' or 'UNKNOWN'", status)))
+		}
+
+		if !isOpsgeniePriority(priority) {
+			errs = append(errs, errors.New(fmt.Sprintf("key 'priorities.%s': unknown priority '%s', must be "+
+				"one of 'P1', 'P2', 'P3', 'P4' or 'P5'", status, priority)))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func isOpsgeniePriority(priority string) bool {
+	switch priority {
+	case "P1", "P2", "P3", "P4", "P5":
+		return true
+	}
+	return false
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "opsgenie",
+		Config: func() interface{} { return &OpsgenieConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewOpsgenieHandler(config.(*OpsgenieConfig)), nil
+		},
+	})
+}
+
+// NewOpsgenieHandler creates an opsgenieHandler instance
+func NewOpsgenieHandler(config *OpsgenieConfig) *Handler {
+	o := &opsgenieHandler{
+		ApiKey:     config.ApiKey,
+		URL:        strings.TrimSuffix(config.URL, "/"),
+		Source:     config.Source,
+		Team:       config.Team,
+		Tags:       config.Tags,
+		Priorities: make(map[string]string),
+		client:     &http.Client{Timeout: config.Timeout},
+	}
+
+	if o.URL == "" {
+		o.URL = defaultOpsgenieURL
+	}
+
+	if o.Source == "" {
+		o.Source = "uberwachen"
+	}
+
+	for status, priority := range opsgeniePriorities {
+		o.Priorities[status] = priority
+	}
+	for status, priority := range config.Priorities {
+		o.Priorities[status] = priority
+	}
+
+	if o.client.Timeout == 0 {
+		o.client.Timeout = defaultHTTPTimeout
+	}
+
+	return &Handler{
+		Type:    "opsgenie",
+		Handler: o,
+	}
+}
+
+type opsgenieResponder struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type opsgenieAlert struct {
+	Message     string              `json:"message"`
+	Alias       string              `json:"alias"`
+	Description string              `json:"description,omitempty"`
+	Responders  []opsgenieResponder `json:"responders,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Details     map[string]string   `json:"details,omitempty"`
+	Entity      string              `json:"entity,omitempty"`
+	Source      string              `json:"source,omitempty"`
+	Priority    string              `json:"priority,omitempty"`
+}
+
+type opsgenieNote struct {
+	Note   string `json:"note"`
+	Source string `json:"source,omitempty"`
+}
+
+// Send creates an Opsgenie alert for new incidents, adds
+// a note to it on updates and closes it on resolve
+func (o *opsgenieHandler) Send(msg *Message) error {
+	alias := dedupKey(msg)
+	path := "/v2/alerts"
+	var body interface{}
+
+	switch msg.Type {
+	case MsgTypeResolve:
+		path = fmt.Sprintf("/v2/alerts/%s/close?identifierType=alias", url.PathEscape(alias))
+		body = &opsgenieNote{Note: msg.Title, Source: o.Source}
+	case MsgTypeUpdate:
+		path = fmt.Sprintf("/v2/alerts/%s/notes?identifierType=alias", url.PathEscape(alias))
+		body = &opsgenieNote{Note: fmt.Sprintf("%s\n%s", msg.Title, msg.Body), Source: o.Source}
+	default:
+		body = o.alert(alias, msg)
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		"Authorization": "GenieKey " + o.ApiKey,
+	}
+	_, err = doRequest(o.client, http.MethodPost, o.URL+path, headers, b)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Opsgenie request for alert '%s' successfully sent", alias)
+	return nil
+}
+
+func (o *opsgenieHandler) alert(alias string, msg *Message) *opsgenieAlert {
+	alert := &opsgenieAlert{
+		Message:     truncate(msg.Title, opsgenieMaxMessage),
+		Alias:       alias,
+		Description: msg.Body,
+		Tags:        append([]string{}, o.Tags...),
+		Source:      o.Source,
+		Priority:    o.Priorities["CRITICAL"],
+	}
+
+	if o.Team != "" {
+		alert.Responders = []opsgenieResponder{{Name: o.Team, Type: "team"}}
+	}
+
+	if msg.Check != nil {
+		alert.Entity = msg.Check.Name
+		alert.Priority = o.Priorities[StatusName(msg.Check.Status)]
+
+		if len(msg.Check.Labels) > 0 {
+			alert.Details = msg.Check.Labels.Copy()
+		}
+
+		keys := make([]string, 0, len(msg.Check.Labels))
+		for k := range msg.Check.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			v := msg.Check.Labels[k]
+			if k == "priority" && isOpsgeniePriority(v) {
+				alert.Priority = v
+				continue
+			}
+			alert.Tags = append(alert.Tags, fmt.Sprintf("%s:%s", k, v))
+		}
+	}
+
+	return alert
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type opsgenieRequest struct {
+	path string
+	auth string
+	body map[string]interface{}
+}
+
+func opsgenieServer(t *testing.T, req *opsgenieRequest) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req.path = r.URL.RequestURI()
+		req.auth = r.Header.Get("Authorization")
+		req.body = map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&req.body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOpsgenieHandlerSend(t *testing.T) {
+	tests := []struct {
+		msgType string
+		path    string
+		key     string
+		value   string
+	}{
+		{MsgTypeNew, "/v2/alerts", "alias", "abc/1"},
+		{MsgTypeUpdate, "/v2/alerts/abc%2F1/notes?identifierType=alias", "note", "disk failed\noutput"},
+		{MsgTypeResolve, "/v2/alerts/abc%2F1/close?identifierType=alias", "note", "disk failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.msgType, func(t *testing.T) {
+			req := &opsgenieRequest{}
+			srv := opsgenieServer(t, req)
+
+			h := NewOpsgenieHandler(&OpsgenieConfig{ApiKey: "key", URL: srv.URL + "/"})
+			msg := testCheckMessage(tt.msgType, "disk failed", "disk", StatusCritical, "abc/1")
+			if err := h.Handler.Send(msg); err != nil {
+				t.Fatal(err)
+			}
+
+			if req.path != tt.path {
+				t.Errorf("expected path '%s', got '%s'", tt.path, req.path)
+			}
+			if req.auth != "GenieKey key" {
+				t.Errorf("unexpected authorization '%s'", req.auth)
+			}
+			if req.body[tt.key] != tt.value || req.body["source"] != "uberwachen" {
+				t.Errorf("unexpected body %v", req.body)
+			}
+		})
+	}
+}
+
+func TestOpsgenieHandlerAlert(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		labels   Labels
+		priority string
+		tags     []string
+	}{
+		{"status", StatusCritical, nil, "P2", []string{"base"}},
+		{"default status", StatusWarning, nil, "P3", []string{"base"}},
+		{"label", StatusCritical, Labels{"priority": "P4", "team": "ops"}, "P4", []string{"base", "team:ops"}},
+		{"invalid label", StatusCritical, Labels{"priority": "high"}, "P2", []string{"base", "priority:high"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewOpsgenieHandler(&OpsgenieConfig{
+				ApiKey:     "key",
+				Team:       "ops",
+				Tags:       []string{"base"},
+				Priorities: map[string]string{"CRITICAL": "P2"},
+			})
+			o := h.Handler.(*opsgenieHandler)
+
+			c := NewCheck()
+			c.Name = "disk"
+			c.Status = tt.status
+			c.Labels = tt.labels
+			alert := o.alert("abc", &Message{Type: MsgTypeNew, Title: "disk failed", Check: c})
+
+			if alert.Priority != tt.priority {
+				t.Errorf("expected priority %s, got %s", tt.priority, alert.Priority)
+			}
+			if !reflect.DeepEqual(alert.Tags, tt.tags) {
+				t.Errorf("expected tags %v, got %v", tt.tags, alert.Tags)
+			}
+			if alert.Entity != "disk" || len(alert.Responders) != 1 || alert.Responders[0].Name != "ops" {
+				t.Errorf("unexpected alert %+v", alert)
+			}
+			if !reflect.DeepEqual(o.Tags, []string{"base"}) {
+				t.Errorf("handler tags changed to %v", o.Tags)
+			}
+		})
+	}
+}
+
+func TestOpsgenieHandlerAlertTruncate(t *testing.T) {
+	h := NewOpsgenieHandler(&OpsgenieConfig{ApiKey: "key"})
+	title := strings.Repeat("✅", opsgenieMaxMessage+1)
+	alert := h.Handler.(*opsgenieHandler).alert("abc", &Message{Title: title})
+
+	if n := utf8.RuneCountInString(alert.Message); n != opsgenieMaxMessage || !utf8.ValidString(alert.Message) {
+		t.Errorf("expected a valid message of %d characters, got %d", opsgenieMaxMessage, n)
+	}
+	if alert.Priority != "P1" {
+		t.Errorf("expected priority P1, got %s", alert.Priority)
+	}
+}
+
+func TestOpsgenieConfigValidate(t *testing.T) {
+	tests := []struct {
+		priorities map[string]string
+		err        string
+	}{
+		{map[string]string{"WARNING": "P4"}, ""},
+		{map[string]string{"BROKEN": "P4"}, "key 'priorities.BROKEN': unknown status"},
+		{map[string]string{"WARNING": "P6"}, "key 'priorities.WARNING': unknown priority 'P6'"},
+	}
+
+	for _, tt := range tests {
+		err := (&OpsgenieConfig{Priorities: tt.priorities}).Validate()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%v: expected error '%s', got %v", tt.priorities, tt.err, err)
+		}
+	}
+}
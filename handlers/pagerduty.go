@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/uberwachen/util"
+)
+
+const (
+	defaultPagerDutyURL = "https://events.pagerduty.com"
+
+	// pagerDutyMaxSummary is the maximum length of the summary of an event
+	pagerDutyMaxSummary = 1024
+)
+
+// pagerDutySeverities maps check statuses to PagerDuty severities
+var pagerDutySeverities = map[string]string{
+	"OK":       "info",
+	"WARNING":  "warning",
+	"CRITICAL": "critical",
+	"UNKNOWN":  "error",
+}
+
+// pagerDutyHandler represents a PagerDuty Events API v2 handler
+type pagerDutyHandler struct {
+	*Handler
+	RoutingKey string            `json:"-" bson:"-"`
+	URL        string            `json:"url"`
+	Source     string            `json:"source"`
+	Component  string            `json:"component"`
+	Group      string            `json:"group"`
+	Class      string            `json:"class"`
+	Severities map[string]string `json:"severities"`
+	client     *http.Client
+}
+
+// PagerDutyConfig holds the configuration of a PagerDuty handler
+type PagerDutyConfig struct {
+	RoutingKey string            `mapstructure:"routingKey" validate:"required"`
+	URL        string            `mapstructure:"url"`
+	Source     string            `mapstructure:"source"`
+	Component  string            `mapstructure:"component"`
+	Group      string            `mapstructure:"group"`
+	Class      string            `mapstructure:"class"`
+	Severities map[string]string `mapstructure:"severities"`
+	Timeout    time.Duration     `mapstructure:"timeout"`
+}
+
+// Validate checks the severities map statuses to PagerDuty severities
+func (c *PagerDutyConfig) Validate() error {
+	var errs util.Errors
+
+	for status, severity := range c.Severities {
+		if _, ok := pagerDutySeverities[status]; !ok {
+			errs = append(errs, errors.New(fmt.Sprintf("key 'severities.%s': unknown status, must be one of "+
+				"'OK', 'WARNING', 'CRITICAL' or 'UNKNOWN'", status)))
+		}
+
+		switch severity {
+		case "critical", "error", "warning", "info":
+		default:
+			errs = append(errs, errors.New(fmt.Sprintf("key 'severities.%s': unknown severity '%s', must be one "+
+				"of 'critical', 'error', 'warning' or 'info'", status, severity)))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "pagerduty",
+		Config: func() interface{} { return &PagerDutyConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewPagerDutyHandler(config.(*PagerDutyConfig)), nil
+		},
+	})
+}
+
+// NewPagerDutyHandler creates a pagerDutyHandler instance
+func NewPagerDutyHandler(config *PagerDutyConfig) *Handler {
+	p := &pagerDutyHandler{
+		RoutingKey: config.RoutingKey,
+		URL:        strings.TrimSuffix(config.URL, "/"),
+		Source:     config.Source,
+		Component:  config.Component,
+		Group:      config.Group,
+		Class:      config.Class,
+		Severities: make(map[string]string),
+		client:     &http.Client{Timeout: config.Timeout},
+	}
+
+	if p.URL == "" {
+		p.URL = defaultPagerDutyURL
+	}
+
+	if p.Source == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "uberwachen"
+		}
+		p.Source = hostname
+	}
+
+	for status, severity := range pagerDutySeverities {
+		p.Severities[status] = severity
+	}
+	for status, severity := range config.Severities {
+		p.Severities[status] = severity
+	}
+
+	if p.client.Timeout == 0 {
+		p.client.Timeout = defaultHTTPTimeout
+	}
+
+	return &Handler{
+		Type:    "pagerduty",
+		Handler: p,
+	}
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+	Links       []pagerDutyLink   `json:"links,omitempty"`
+}
+
+type pagerDutyLink struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// Send triggers a PagerDuty alert for new incidents and resolves it on resolve
+func (p *pagerDutyHandler) Send(msg *Message) error {
+	event := &pagerDutyEvent{
+		RoutingKey:  p.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    dedupKey(msg),
+	}
+
+	if msg.Type == MsgTypeResolve {
+		event.EventAction = "resolve"
+	} else {
+		event.Payload = &pagerDutyPayload{
+			Summary:   truncate(msg.Title, pagerDutyMaxSummary),
+			Source:    p.Source,
+			Severity:  p.Severities["CRITICAL"],
+			Component: p.Component,
+			Group:     p.Group,
+			Class:     p.Class,
+			CustomDetails: map[string]interface{}{
+				"output": msg.Body,
+			},
+		}
+
+		if msg.CheckName != "" {
+			event.Payload.Severity = p.Severities[msg.Severity]
+			details := event.Payload.CustomDetails
+			details["check"] = msg.CheckName
+			details["status"] = msg.Severity
+			details["previous_status"] = StatusName(msg.PreviousStatus)
+			details["duration"] = msg.Duration
+			details["attempts"] = msg.Attempts
+			if msg.IncidentID != "" {
+				details["incident"] = msg.IncidentID
+			}
+			if !msg.StartedAt.IsZero() {
+				details["started_at"] = msg.StartedAt.Format(time.RFC3339)
+			}
+			if msg.RunbookURL != "" {
+				details["runbook_url"] = msg.RunbookURL
+				event.Links = []pagerDutyLink{{Href: msg.RunbookURL, Text: "Runbook"}}
+			}
+			if !msg.ExecutedAt.IsZero() {
+				event.Payload.Timestamp = msg.ExecutedAt.Format(time.RFC3339)
+			}
+			if len(msg.Labels) > 0 {
+				details["labels"] = msg.Labels
+			}
+		}
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	_, err = doRequest(p.client, http.MethodPost, p.URL+"/v2/enqueue", headers, b)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("PagerDuty event '%s' successfully sent with dedup key '%s'", event.EventAction, event.DedupKey)
+	return nil
+}
+
+// dedupKey returns the key identifying the alert of a message in external services
+func dedupKey(msg *Message) string {
+	if msg.IncidentID != "" {
+		return msg.IncidentID
+	}
+
+	if msg.CheckName != "" {
+		return msg.CheckName
+	}
+
+	return msg.Title
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// testCheckMessage returns a message of a check, and of its incident if id is set
+func testCheckMessage(msgType, title, name string, status int, id string) *Message {
+	msg := &Message{Type: msgType, Title: title, Body: "output"}
+	if name != "" {
+		c := NewCheck()
+		c.Name = name
+		c.Status = status
+		msg.Check = c
+		msg.CheckName = name
+		msg.Status = status
+		msg.Severity = StatusName(status)
+		if id != "" {
+			msg.Incident = NewIncident(c)
+			msg.Incident.ID = id
+			msg.IncidentID = id
+		}
+	}
+	return msg
+}
+
+func TestPagerDutyHandlerSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		msg      *Message
+		action   string
+		dedupKey string
+		severity string
+	}{
+		{
+			name:     "trigger",
+			msg:      testCheckMessage(MsgTypeNew, "disk failed", "disk", 1, "abc"),
+			action:   "trigger",
+			dedupKey: "abc",
+			severity: "warning",
+		},
+		{
+			name:     "custom severity",
+			msg:      testCheckMessage(MsgTypeNew, "disk failed", "disk", 2, ""),
+			action:   "trigger",
+			dedupKey: "disk",
+			severity: "error",
+		},
+		{
+			name:     "manual",
+			msg:      testCheckMessage(MsgTypeNew, "test", "", 0, ""),
+			action:   "trigger",
+			dedupKey: "test",
+			severity: "error",
+		},
+		{
+			name:     "resolve",
+			msg:      testCheckMessage(MsgTypeResolve, "disk passed", "disk", 0, "abc"),
+			action:   "resolve",
+			dedupKey: "abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			event := &pagerDutyEvent{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				_ = json.NewDecoder(r.Body).Decode(event)
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+
+			h := NewPagerDutyHandler(&PagerDutyConfig{
+				RoutingKey: "key",
+				URL:        srv.URL + "/",
+				Source:     "host",
+				Severities: map[string]string{"CRITICAL": "error"},
+			})
+			if err := h.Handler.Send(tt.msg); err != nil {
+				t.Fatal(err)
+			}
+
+			if path != "/v2/enqueue" {
+				t.Errorf("unexpected path '%s'", path)
+			}
+			if event.RoutingKey != "key" || event.EventAction != tt.action || event.DedupKey != tt.dedupKey {
+				t.Errorf("unexpected event %+v", event)
+			}
+			if tt.action == "resolve" {
+				if event.Payload != nil {
+					t.Errorf("expected no payload, got %+v", event.Payload)
+				}
+				return
+			}
+			if event.Payload == nil || event.Payload.Severity != tt.severity || event.Payload.Source != "host" {
+				t.Errorf("unexpected payload %+v", event.Payload)
+			}
+		})
+	}
+}
+
+func TestPagerDutyHandlerSendDetails(t *testing.T) {
+	event := &pagerDutyEvent{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(event)
+	}))
+	defer srv.Close()
+
+	msg := testCheckMessage(MsgTypeNew, strings.Repeat("é", pagerDutyMaxSummary+10), "disk", 2, "")
+	msg.Labels = Labels{"team": "ops"}
+	msg.RunbookURL = "https://example.com/runbooks/disk"
+	h := NewPagerDutyHandler(&PagerDutyConfig{RoutingKey: "key", URL: srv.URL})
+	if err := h.Handler.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	p := event.Payload
+	if n := utf8.RuneCountInString(p.Summary); n != pagerDutyMaxSummary || !utf8.ValidString(p.Summary) {
+		t.Errorf("expected a valid summary of %d characters, got %d", pagerDutyMaxSummary, n)
+	}
+	if p.CustomDetails["output"] != "output" || p.CustomDetails["check"] != "disk" ||
+		p.CustomDetails["status"] != "CRITICAL" || p.CustomDetails["runbook_url"] != msg.RunbookURL {
+		t.Errorf("unexpected details %v", p.CustomDetails)
+	}
+	if len(event.Links) != 1 || event.Links[0].Href != msg.RunbookURL {
+		t.Errorf("unexpected links %v", event.Links)
+	}
+}
+
+func TestPagerDutyConfigValidate(t *testing.T) {
+	tests := []struct {
+		severities map[string]string
+		err        string
+	}{
+		{map[string]string{"WARNING": "info"}, ""},
+		{map[string]string{"BROKEN": "info"}, "key 'severities.BROKEN': unknown status"},
+		{map[string]string{"WARNING": "high"}, "key 'severities.WARNING': unknown severity 'high'"},
+	}
+
+	for _, tt := range tests {
+		err := (&PagerDutyConfig{Severities: tt.severities}).Validate()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%v: expected error '%s', got %v", tt.severities, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Perfdata is a performance metric reported by a check after a '|' in its
+// output, in the Nagios plugins format: 'label'=value[UOM];[warn];[crit];[min];[max]
+type Perfdata struct {
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit,omitempty"`
+	Warn  string  `json:"warn,omitempty"`
+	Crit  string  `json:"crit,omitempty"`
+	Min   string  `json:"min,omitempty"`
+	Max   string  `json:"max,omitempty"`
+}
+
+var perfdataRe = regexp.MustCompile(`('[^']+'|[^\s=]+)=(\S+)`)
+
+var perfdataValueRe = regexp.MustCompile(`^(-?[0-9.]+)([a-zA-Z%]*)$`)
+
+// ParsePerfdata returns the performance metrics of a check output,
+// ignoring the ones that can't be parsed
+func ParsePerfdata(output string) []Perfdata {
+	var perfdata []Perfdata
+
+	for _, line := range strings.Split(output, "\n") {
+		i := strings.Index(line, "|")
+		if i < 0 {
+			continue
+		}
+
+		for _, m := range perfdataRe.FindAllStringSubmatch(line[i+1:], -1) {
+			fields := strings.Split(m[2], ";")
+			v := perfdataValueRe.FindStringSubmatch(fields[0])
+			if v == nil {
+				continue
+			}
+
+			value, err := strconv.ParseFloat(v[1], 64)
+			if err != nil {
+				continue
+			}
+
+			p := Perfdata{
+				Label: strings.Trim(m[1], "'"),
+				Value: value,
+				Unit:  v[2],
+			}
+			for j, f := range []*string{&p.Warn, &p.Crit, &p.Min, &p.Max} {
+				if j+1 < len(fields) {
+					*f = fields[j+1]
+				}
+			}
+			perfdata = append(perfdata, p)
+		}
+	}
+
+	return perfdata
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultPipeTimeout is used when no timeout is configured
+const defaultPipeTimeout = 30 * time.Second
+
+// pipeHandler represents a handler running a command with the event on stdin
+type pipeHandler struct {
+	*Handler
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"-" bson:"-"`
+	Timeout time.Duration     `json:"timeout"`
+}
+
+// PipeConfig holds the configuration of a pipe handler
+type PipeConfig struct {
+	Command string            `mapstructure:"command" validate:"required"`
+	Args    []string          `mapstructure:"args"`
+	Env     map[string]string `mapstructure:"env"`
+	Timeout time.Duration     `mapstructure:"timeout"`
+}
+
+// Validate checks the command can be found
+func (c *PipeConfig) Validate() error {
+	if c.Command == "" {
+		return nil
+	}
+
+	if _, err := exec.LookPath(c.Command); err != nil {
+		return errors.New(fmt.Sprintf("key 'command': %v", err))
+	}
+
+	return nil
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "pipe",
+		Config: func() interface{} { return &PipeConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewPipeHandler(config.(*PipeConfig)), nil
+		},
+	})
+}
+
+// NewPipeHandler creates a pipeHandler instance
+func NewPipeHandler(config *PipeConfig) *Handler {
+	p := &pipeHandler{
+		Command: config.Command,
+		Args:    config.Args,
+		Env:     config.Env,
+		Timeout: config.Timeout,
+	}
+
+	if p.Timeout == 0 {
+		p.Timeout = defaultPipeTimeout
+	}
+
+	return &Handler{
+		Type:    "pipe",
+		Handler: p,
+	}
+}
+
+// pipeEvent is written as JSON to the command's stdin
+type pipeEvent struct {
+	Check    *Check    `json:"check,omitempty"`
+	Incident *Incident `json:"incident,omitempty"`
+	Message  *Message  `json:"message"`
+}
+
+// Send runs the command with the event as JSON on its stdin,
+// a non-zero exit code is returned as an error
+func (p *pipeHandler) Send(msg *Message) error {
+	m := *msg
+	m.Check = nil
+	m.Incident = nil
+
+	b, err := json.Marshal(&pipeEvent{
+		Check:    msg.Check,
+		Incident: msg.Incident,
+		Message:  &m,
+	})
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	cmd := exec.Command(p.Command, p.Args...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// The command runs in its own process group, so the processes it
+	// started holding its output open are killed with it on timeout
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Env = append(os.Environ(), "UBERWACHEN_MESSAGE_TYPE="+msg.Type)
+	if msg.Check != nil {
+		cmd.Env = append(cmd.Env, "UBERWACHEN_CHECK_NAME="+msg.Check.Name)
+	}
+	for k, v := range p.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	timer := time.NewTimer(p.Timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-done:
+	case <-timer.C:
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		return errors.New(fmt.Sprintf("command '%s' timed out after %s", p.Command, p.Timeout))
+	}
+
+	out := strings.TrimSpace(output.String())
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return errors.New(fmt.Sprintf("command '%s' exited with code %d: %s",
+				p.Command, exitErr.ExitCode(), out))
+		}
+		return err
+	}
+
+	log.Debug().Msgf("Command '%s' successfully ran: output: '%s'", p.Command, out)
+	return nil
+}
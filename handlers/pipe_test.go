@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPipeHandlerSend(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "event.json")
+
+	h := NewPipeHandler(&PipeConfig{
+		Command: "sh",
+		Args:    []string{"-c", `cat > "$OUT"; echo "$UBERWACHEN_MESSAGE_TYPE $UBERWACHEN_CHECK_NAME" >> "$OUT.env"`},
+		Env:     map[string]string{"OUT": out},
+	})
+
+	c := NewCheck()
+	c.Name = "disk"
+	msg := &Message{Type: MsgTypeNew, Title: "title", Check: c, Incident: NewIncident(c)}
+	if err := h.Handler.Send(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event struct {
+		Check   *Check   `json:"check"`
+		Message *Message `json:"message"`
+	}
+	if err := json.Unmarshal(b, &event); err != nil {
+		t.Fatalf("invalid event: %v", err)
+	}
+	if event.Check == nil || event.Check.Name != "disk" {
+		t.Errorf("unexpected check %+v", event.Check)
+	}
+	if event.Message == nil || event.Message.Title != "title" || event.Message.Check != nil {
+		t.Errorf("unexpected message %+v", event.Message)
+	}
+
+	env, err := ioutil.ReadFile(out + ".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(env)) != "new disk" {
+		t.Errorf("unexpected environment '%s'", env)
+	}
+}
+
+func TestPipeHandlerExitCode(t *testing.T) {
+	h := NewPipeHandler(&PipeConfig{Command: "sh", Args: []string{"-c", "echo failed; exit 3"}})
+
+	err := h.Handler.Send(&Message{Type: MsgTypeNew})
+	if err == nil || !strings.Contains(err.Error(), "exited with code 3: failed") {
+		t.Errorf("expected exit code error, got %v", err)
+	}
+}
+
+func TestPipeHandlerTimeoutKillsChildren(t *testing.T) {
+	// The background sleep inherits the output of the shell and keeps it open
+	h := NewPipeHandler(&PipeConfig{
+		Command: "sh",
+		Args:    []string{"-c", "sleep 30 & sleep 30"},
+		Timeout: 100 * time.Millisecond,
+	})
+
+	start := time.Now()
+	err := h.Handler.Send(&Message{Type: MsgTypeNew})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("send returned after %s", d)
+	}
+}
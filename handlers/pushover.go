@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const defaultPushoverURL = "https://api.pushover.net"
+
+// pushoverPriorities maps message levels to Pushover priorities
+var pushoverPriorities = map[int]int{1: -2, 2: -1, 3: -1, 4: 0, 5: 1}
+
+// pushoverHandler represents a Pushover push notification handler
+type pushoverHandler struct {
+	*Handler
+	URL    string `json:"url"`
+	Token  string `json:"-" bson:"-"`
+	User   string `json:"-" bson:"-"`
+	Device string `json:"device"`
+	Sound  string `json:"sound"`
+	client *http.Client
+}
+
+// PushoverConfig holds the configuration of a Pushover handler
+type PushoverConfig struct {
+	URL     string        `mapstructure:"url"`
+	Token   string        `mapstructure:"token" validate:"required"`
+	User    string        `mapstructure:"user" validate:"required"`
+	Device  string        `mapstructure:"device"`
+	Sound   string        `mapstructure:"sound"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "pushover",
+		Config: func() interface{} { return &PushoverConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewPushoverHandler(config.(*PushoverConfig)), nil
+		},
+	})
+}
+
+// NewPushoverHandler creates a pushoverHandler instance
+func NewPushoverHandler(config *PushoverConfig) *Handler {
+	p := &pushoverHandler{
+		URL:    strings.TrimSuffix(config.URL, "/"),
+		Token:  config.Token,
+		User:   config.User,
+		Device: config.Device,
+		Sound:  config.Sound,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+
+	if p.URL == "" {
+		p.URL = defaultPushoverURL
+	}
+
+	if p.client.Timeout == 0 {
+		p.client.Timeout = defaultHTTPTimeout
+	}
+
+	return &Handler{
+		Type:    "pushover",
+		Handler: p,
+	}
+}
+
+// Send pushes the message through Pushover
+func (p *pushoverHandler) Send(msg *Message) error {
+	body := msg.Body
+	if body == "" {
+		body = msg.Title // Pushover requires a message
+	}
+
+	form := url.Values{}
+	form.Set("token", p.Token)
+	form.Set("user", p.User)
+	form.Set("title", msg.Title)
+	form.Set("message", body)
+	form.Set("priority", strconv.Itoa(pushoverPriorities[messageLevel(msg)]))
+	if p.Device != "" {
+		form.Set("device", p.Device)
+	}
+	if p.Sound != "" {
+		form.Set("sound", p.Sound)
+	}
+
+	headers := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+	_, err := doRequest(p.client, http.MethodPost, p.URL+"/1/messages.json", headers, []byte(form.Encode()))
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msg("Message successfully pushed to Pushover")
+	return nil
+}
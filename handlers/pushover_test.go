@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestPushoverHandlerSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   PushoverConfig
+		msg      *Message
+		body     string
+		expected url.Values
+	}{
+		{
+			name:   "critical",
+			config: PushoverConfig{Token: "token", User: "user", Device: "phone", Sound: "siren"},
+			msg:    testCheckMessage(MsgTypeNew, "disk failed", "disk", StatusCritical, ""),
+			body:   "output",
+			expected: url.Values{"token": {"token"}, "user": {"user"}, "title": {"disk failed"}, "message": {"output"},
+				"priority": {"1"}, "device": {"phone"}, "sound": {"siren"}},
+		},
+		{
+			name:   "empty body",
+			config: PushoverConfig{Token: "token", User: "user"},
+			msg:    testCheckMessage(MsgTypeResolve, "disk passed", "disk", StatusOK, ""),
+			expected: url.Values{"token": {"token"}, "user": {"user"}, "title": {"disk passed"}, "message": {"disk passed"},
+				"priority": {"-1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			var form url.Values
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				_ = r.ParseForm()
+				form = r.PostForm
+			}))
+			defer srv.Close()
+
+			tt.msg.Body = tt.body
+			tt.config.URL = srv.URL
+			h := NewPushoverHandler(&tt.config)
+			if err := h.Handler.Send(tt.msg); err != nil {
+				t.Fatal(err)
+			}
+
+			if path != "/1/messages.json" {
+				t.Errorf("unexpected path '%s'", path)
+			}
+			if form.Encode() != tt.expected.Encode() {
+				t.Errorf("expected form %v, got %v", tt.expected, form)
+			}
+		})
+	}
+}
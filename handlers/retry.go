@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/jpillora/backoff"
+	"github.com/rs/zerolog/log"
+)
+
+// RetryPolicy is how many times and how often a handler tries to send a message
+type RetryPolicy struct {
+	Attempts int           `json:"attempts"`
+	MinDelay time.Duration `json:"min_delay"`
+	MaxDelay time.Duration `json:"max_delay"`
+	Factor   float64       `json:"factor"`
+}
+
+// DefaultRetryPolicy is used by the handlers that don't define their own
+var DefaultRetryPolicy = &RetryPolicy{
+	Attempts: 3,
+	MinDelay: time.Second,
+	MaxDelay: time.Minute,
+	Factor:   2,
+}
+
+// retryPolicy returns the retry policy of a handler
+func (h *Handler) retryPolicy() *RetryPolicy {
+	if h.Retry == nil {
+		return DefaultRetryPolicy
+	}
+	return h.Retry
+}
+
+// backoff returns the exponential backoff between the attempts
+func (p *RetryPolicy) backoff() *backoff.Backoff {
+	return &backoff.Backoff{
+		Min:    p.MinDelay,
+		Max:    p.MaxDelay,
+		Factor: p.Factor,
+		Jitter: true,
+	}
+}
+
+// retry queues a failed job again once its backoff delay elapsed, without holding
+// a worker while waiting. It returns false when the job has no attempts left.
+func (q *queue) retry(j *job, err error) bool {
+	policy := j.handler.retryPolicy()
+	if j.attempts >= policy.Attempts {
+		return false
+	}
+
+	if j.backoff == nil {
+		j.backoff = policy.backoff()
+	}
+
+	d := j.backoff.Duration()
+	log.Warn().Msgf("Error sending message with handler '%s' (attempt %d/%d), retrying in %s: %v",
+		j.handler.Name, j.attempts, policy.Attempts, d, err)
+
+	// never blocks as the job is still counted as pending
+	time.AfterFunc(d, func() { q.jobs <- j })
+	return true
+}
@@ -1,61 +1,184 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/mail"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 	"github.com/sendgrid/sendgrid-go"
-	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	sgmail "github.com/sendgrid/sendgrid-go/helpers/mail"
+
+	"github.com/alexferl/uberwachen/util"
 )
 
+const defaultSendGridApiHost = "https://api.sendgrid.com"
+
 // sendGridHandler represents a SendGrid handler
 type sendGridHandler struct {
 	*Handler
-	ApiKey          string `json:"api_key" bson:"api_key"`
-	SubjectPrefix   string `json:"subject_prefix" bson:"subject_prefix"`
-	From            string `json:"from"`
-	FromName        string `json:"from_name" bson:"from_name"`
-	To              string `json:"to"`
-	ToName          string `json:"to_name" bson:"to_name"`
-	NotifyOnResolve bool   `json:"notify_on_resolve" bson:"notify_on_resolve"`
+	ApiKey        string             `json:"api_key" bson:"api_key"`
+	ApiHost       string             `json:"api_host" bson:"api_host"`
+	SubjectPrefix string             `json:"subject_prefix" bson:"subject_prefix"`
+	From          string             `json:"from"`
+	FromName      string             `json:"from_name" bson:"from_name"`
+	To            []string           `json:"to"`
+	ToName        string             `json:"to_name" bson:"to_name"`
+	Cc            []string           `json:"cc"`
+	Bcc           []string           `json:"bcc"`
+	template      *template.Template `json:"-" bson:"-"`
 }
 
-// NewSendGridHandler creates a sendGridHandler instance
-func NewSendGridHandler(apiKey, subjectPrefix, from, fromName, to, toName string, notifyOnResolve bool) *Handler {
-	return &Handler{
-		Type: "sendgrid",
-		Handler: &sendGridHandler{
-			ApiKey:          apiKey,
-			SubjectPrefix:   subjectPrefix,
-			From:            from,
-			FromName:        fromName,
-			To:              to,
-			ToName:          toName,
-			NotifyOnResolve: notifyOnResolve,
+// Addresses is a list of email addresses, decoded from a list
+// or from a single string of comma separated addresses
+type Addresses []string
+
+// SendGridConfig holds the configuration of a SendGrid handler.
+// ToName is only used when there is a single To address. Without
+// NotifyOnResolve, the handler's types filter defaults to new and update.
+type SendGridConfig struct {
+	ApiKey          string    `mapstructure:"apiKey" validate:"required"`
+	ApiHost         string    `mapstructure:"apiHost"`
+	SubjectPrefix   string    `mapstructure:"subjectPrefix"`
+	From            string    `mapstructure:"from" validate:"required"`
+	FromName        string    `mapstructure:"fromName"`
+	To              Addresses `mapstructure:"to" validate:"required"`
+	ToName          string    `mapstructure:"toName"`
+	Cc              Addresses `mapstructure:"cc"`
+	Bcc             Addresses `mapstructure:"bcc"`
+	NotifyOnResolve bool      `mapstructure:"notifyOnResolve"`
+	TemplateFile    string    `mapstructure:"templateFile"`
+}
+
+// Validate checks the addresses and the HTML template
+func (c *SendGridConfig) Validate() error {
+	var errs util.Errors
+
+	addresses := map[string][]string{"from": {c.From}, "to": c.To, "cc": c.Cc, "bcc": c.Bcc}
+	for _, key := range []string{"from", "to", "cc", "bcc"} {
+		for _, address := range addresses[key] {
+			if address == "" {
+				continue
+			}
+			if _, err := mail.ParseAddress(address); err != nil {
+				errs = append(errs, errors.New(fmt.Sprintf("key '%s': invalid address '%s': %v", key, address, err)))
+			}
+		}
+	}
+
+	if _, err := c.parseTemplate(); err != nil {
+		errs = append(errs, errors.New(fmt.Sprintf("key 'templateFile': %v", err)))
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// parseTemplate parses the HTML template file, or the default template if none is set
+func (c *SendGridConfig) parseTemplate() (*template.Template, error) {
+	text := defaultEmailTemplate
+	if c.TemplateFile != "" {
+		b, err := ioutil.ReadFile(c.TemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		text = string(b)
+	}
+
+	return template.New("email").Parse(text)
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "sendgrid",
+		Config: func() interface{} { return &SendGridConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewSendGridHandler(config.(*SendGridConfig))
 		},
+	})
+}
+
+// NewSendGridHandler creates a sendGridHandler instance
+func NewSendGridHandler(config *SendGridConfig) (*Handler, error) {
+	t, err := config.parseTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	sg := &sendGridHandler{
+		ApiKey:        config.ApiKey,
+		ApiHost:       config.ApiHost,
+		SubjectPrefix: config.SubjectPrefix,
+		From:          config.From,
+		FromName:      config.FromName,
+		To:            config.To,
+		ToName:        config.ToName,
+		Cc:            config.Cc,
+		Bcc:           config.Bcc,
+		template:      t,
 	}
+
+	if sg.ApiHost == "" {
+		sg.ApiHost = defaultSendGridApiHost
+	}
+
+	h := &Handler{
+		Type:    "sendgrid",
+		Handler: sg,
+	}
+
+	if !config.NotifyOnResolve {
+		h.Filter = &Filter{Types: []string{MsgTypeNew, MsgTypeUpdate}}
+	}
+
+	return h, nil
 }
 
 // Send sends an email via the SendGrid API
 func (sg *sendGridHandler) Send(msg *Message) error {
-	if sg.NotifyOnResolve {
-		subject := fmt.Sprintf("%s %s", sg.SubjectPrefix, msg.Title)
-
-		from := mail.NewEmail(sg.FromName, sg.From)
-		to := mail.NewEmail(sg.ToName, sg.To)
-		content := mail.NewContent("text/plain", msg.Body)
-		m := mail.NewV3MailInit(from, subject, to, content)
-
-		request := sendgrid.GetRequest(sg.ApiKey, "/v3/mail/send", "https://api.sendgrid.com")
-		request.Method = "POST"
-		request.Body = mail.GetRequestBody(m)
-		response, err := sendgrid.API(request)
-		if err != nil {
-			return err
-		} else {
-			log.Debug().Msgf("SendGrid returned status code: '%d' body: '%s' headers: '%s'",
-				response.StatusCode, response.Body, response.Headers)
+	html, err := renderEmail(sg.template, msg)
+	if err != nil {
+		return err
+	}
+
+	m := sgmail.NewV3Mail()
+	m.SetFrom(sgmail.NewEmail(sg.FromName, sg.From))
+	m.Subject = strings.TrimSpace(fmt.Sprintf("%s %s", sg.SubjectPrefix, msg.Title))
+
+	p := sgmail.NewPersonalization()
+	for _, address := range sg.To {
+		name := ""
+		if len(sg.To) == 1 {
+			name = sg.ToName
 		}
+		p.AddTos(sgmail.NewEmail(name, address))
 	}
+	for _, address := range sg.Cc {
+		p.AddCCs(sgmail.NewEmail("", address))
+	}
+	for _, address := range sg.Bcc {
+		p.AddBCCs(sgmail.NewEmail("", address))
+	}
+	m.AddPersonalizations(p)
+	m.AddContent(sgmail.NewContent("text/plain", msg.Body), sgmail.NewContent("text/html", html))
+
+	request := sendgrid.GetRequest(sg.ApiKey, "/v3/mail/send", sg.ApiHost)
+	request.Method = "POST"
+	request.Body = sgmail.GetRequestBody(m)
+	response, err := sendgrid.API(request)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return errors.New(fmt.Sprintf("SendGrid returned status code: '%d' body: '%s'",
+			response.StatusCode, response.Body))
+	}
+
+	log.Debug().Msgf("SendGrid returned status code: '%d' body: '%s' headers: '%s'",
+		response.StatusCode, response.Body, response.Headers)
+
 	return nil
 }
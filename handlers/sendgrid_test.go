@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type sendGridRequest struct {
+	Subject          string `json:"subject"`
+	Personalizations []struct {
+		To []struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"to"`
+	} `json:"personalizations"`
+}
+
+func TestSendGridHandlerSend(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		to      Addresses
+		msgType string
+		subject string
+		toName  string
+	}{
+		{"prefix", "[Monitoring]", Addresses{"a@example.com"}, MsgTypeNew, "[Monitoring] Check failed", "Alice"},
+		{"no prefix", "", Addresses{"a@example.com", "b@example.com"}, MsgTypeNew, "Check failed", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *sendGridRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v3/mail/send" || r.Header.Get("Authorization") != "Bearer key" {
+					t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Authorization"))
+				}
+				req = &sendGridRequest{}
+				if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+					t.Error(err)
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+
+			h, err := NewSendGridHandler(&SendGridConfig{
+				ApiKey:        "key",
+				ApiHost:       srv.URL,
+				SubjectPrefix: tt.prefix,
+				From:          "ops@example.com",
+				To:            tt.to,
+				ToName:        "Alice",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := NewCheck()
+			c.Name = "disk"
+			msg := NewMessage(tt.msgType, c, NewIncident(c))
+			msg.Title = "Check failed"
+			if err := h.Handler.Send(msg); err != nil {
+				t.Fatal(err)
+			}
+
+			if req == nil {
+				t.Fatal("no request sent")
+			}
+			if req.Subject != tt.subject {
+				t.Errorf("expected subject '%s', got '%s'", tt.subject, req.Subject)
+			}
+			to := req.Personalizations[0].To
+			if len(to) != len(tt.to) || to[0].Email != tt.to[0] || to[0].Name != tt.toName {
+				t.Errorf("unexpected recipients %+v", to)
+			}
+		})
+	}
+}
+
+func TestSendGridHandlerNotifyOnResolve(t *testing.T) {
+	msg := &Message{Type: MsgTypeResolve, Severity: "OK", Status: StatusOK}
+
+	for _, notify := range []bool{false, true} {
+		h, err := NewSendGridHandler(&SendGridConfig{ApiKey: "key", From: "ops@example.com",
+			To: Addresses{"a@example.com"}, NotifyOnResolve: notify})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if accepts := h.accepts(msg); accepts != notify {
+			t.Errorf("notifyOnResolve %v: resolve accepted %v", notify, accepts)
+		}
+	}
+}
+
+func TestSendGridHandlerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	h, err := NewSendGridHandler(&SendGridConfig{ApiKey: "key", ApiHost: srv.URL, From: "ops@example.com", To: Addresses{"a@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Handler.Send(&Message{Type: MsgTypeNew, Title: "Check failed"}); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+)
+
+// setHandler represents a set of other handlers referenced by name
+type setHandler struct {
+	Handlers []string `json:"handlers"`
+}
+
+// SetConfig holds the configuration of a set handler
+type SetConfig struct {
+	Handlers []string `mapstructure:"handlers" validate:"required"`
+}
+
+// Validate checks the set has handlers, as the required key allows an empty list
+func (c *SetConfig) Validate() error {
+	if c.Handlers != nil && len(c.Handlers) == 0 {
+		return errors.New("key 'handlers': must have at least one handler")
+	}
+	return nil
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "set",
+		Config: func() interface{} { return &SetConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewSetHandler(config.(*SetConfig).Handlers), nil
+		},
+	})
+}
+
+// NewSetHandler creates a setHandler instance
+func NewSetHandler(handlers []string) *Handler {
+	return &Handler{
+		Type: "set",
+		Handler: &setHandler{
+			Handlers: handlers,
+		},
+	}
+}
+
+// Send fails as sets are replaced by their handlers when checks are loaded
+func (s *setHandler) Send(msg *Message) error {
+	return errors.New("handler sets can't send messages, their handlers do")
+}
+
+// Members returns the names of the handlers of a set handler, or nil for other handlers
+func (h *Handler) Members() []string {
+	if s, ok := h.Handler.(*setHandler); ok {
+		return s.Handlers
+	}
+	return nil
+}
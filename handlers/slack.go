@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/nlopes/slack"
 	"github.com/rs/zerolog/log"
@@ -9,54 +12,225 @@ import (
 
 // slackHandler represents a Slack handler
 type slackHandler struct {
-	*Handler
+	*Handler    `json:"-" bson:"-"`
 	Channel     string `json:"channel"`
 	Token       string `json:"token"`
 	BotUsername string `json:"bot_username" bson:"bot_username"`
 	BotIconUrl  string `json:"bot_icon_url" bson:"bot_icon_url"`
+	ApiUrl      string `json:"api_url,omitempty" bson:"api_url,omitempty"`
+	Interactive bool   `json:"interactive"`
 }
 
-// NewSlackHandler creates a slackHandler instance
-func NewSlackHandler(channel, token, botUsername, botIconUrl string) *Handler {
-	return &Handler{
-		Type: "slack",
-		Handler: &slackHandler{
-			Channel:     channel,
-			Token:       token,
-			BotUsername: botUsername,
-			BotIconUrl:  botIconUrl,
+const (
+	// slackMaxFields is the maximum number of fields of a section block
+	slackMaxFields = 10
+	// slackMaxElements is the maximum number of elements of a context block
+	slackMaxElements = 10
+)
+
+// SlackMessage is a message posted to Slack for an incident
+type SlackMessage struct {
+	Channel   string `json:"channel"`
+	Timestamp string `json:"timestamp"`
+}
+
+// SlackConfig holds the configuration of a Slack handler
+type SlackConfig struct {
+	Channel     string `mapstructure:"channel" validate:"required"`
+	Token       string `mapstructure:"token" validate:"required"`
+	BotUsername string `mapstructure:"botUsername"`
+	BotIconUrl  string `mapstructure:"botIconUrl"`
+	ApiUrl      string `mapstructure:"apiUrl"`
+	Interactive bool   `mapstructure:"interactive"`
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "slack",
+		Config: func() interface{} { return &SlackConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			c := config.(*SlackConfig)
+			return NewSlackHandler(c.Channel, c.Token, c.BotUsername, c.BotIconUrl, c.ApiUrl, c.Interactive), nil
 		},
+	})
+}
+
+// NewSlackHandler creates a slackHandler instance
+func NewSlackHandler(channel, token, botUsername, botIconUrl, apiUrl string, interactive bool) *Handler {
+	s := &slackHandler{
+		Channel:     channel,
+		Token:       token,
+		BotUsername: botUsername,
+		BotIconUrl:  botIconUrl,
+		ApiUrl:      apiUrl,
+		Interactive: interactive,
 	}
+
+	h := &Handler{
+		Type:    "slack",
+		Handler: s,
+	}
+	s.Handler = h
+
+	return h
 }
 
-// Send sends a message to a Slack channel
+func (s *slackHandler) client() *slack.Client {
+	if s.ApiUrl != "" {
+		return slack.New(s.Token, slack.OptionAPIURL(strings.TrimSuffix(s.ApiUrl, "/")+"/"))
+	}
+	return slack.New(s.Token)
+}
+
+// Send sends a message to a Slack channel. The message of a new incident is
+// remembered so updates get posted in its thread and it gets updated on resolve.
 func (s *slackHandler) Send(msg *Message) error {
-	text := fmt.Sprintf("%s \n %s", msg.Title, msg.Body)
-	var color string
+	thread := s.thread(msg)
+	if msg.Type == MsgTypeResolve && thread != nil {
+		return s.resolve(msg, thread)
+	}
+
+	attachment, blocks := slackMessage(msg, "")
+	if s.Interactive && msg.Type == MsgTypeNew && msg.Incident != nil {
+		attachment.CallbackID = msg.Incident.ID
+		attachment.Actions = slackActions(msg.Incident)
+	}
 
-	if msg.Type == MsgTypeNew {
-		color = "#DF0101"
-	} else if msg.Type == MsgTypeResolve {
-		color = "#33FF33"
+	options := s.options(attachment, blocks)
+	if msg.Type == MsgTypeUpdate && thread != nil {
+		options = append(options, slack.MsgOptionTS(thread.Timestamp))
 	}
 
-	api := slack.New(s.Token)
+	channelId, timestamp, err := s.client().PostMessage(s.Channel, options...)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Message successfully sent to channel '%s' at '%s'", channelId, timestamp)
+
+	if msg.Type == MsgTypeNew && msg.Incident != nil {
+		m := &SlackMessage{Channel: channelId, Timestamp: timestamp}
+		if msg.Incident.SlackMessages == nil {
+			msg.Incident.SlackMessages = make(map[string]*SlackMessage)
+		}
+		key := handlerKey(s.Name)
+		msg.Incident.SlackMessages[key] = m
+
+		err := msg.Incident.setFields(map[string]interface{}{"slack_messages." + key: m})
+		if err != nil {
+			log.Error().Msgf("Error saving Slack message of incident '%s': %v", msg.Incident.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// resolve updates the original message of an incident
+func (s *slackHandler) resolve(msg *Message, thread *SlackMessage) error {
+	note := fmt.Sprintf("Resolved after %s", formatMinutes(time.Since(msg.Incident.CreatedAt)))
+	attachment, blocks := slackMessage(msg, note)
+
+	channelId, timestamp, _, err := s.client().UpdateMessage(thread.Channel, thread.Timestamp,
+		s.options(attachment, blocks)...)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Message successfully updated in channel '%s' at '%s'", channelId, timestamp)
+	return nil
+}
+
+func (s *slackHandler) options(attachment slack.Attachment, blocks []slack.Block) []slack.MsgOption {
 	params := slack.PostMessageParameters{
 		Username: s.BotUsername,
 		IconURL:  s.BotIconUrl,
 	}
+
+	options := []slack.MsgOption{
+		slack.MsgOptionPostMessageParameters(params),
+		slack.MsgOptionAttachments(attachment),
+	}
+	if len(blocks) > 0 {
+		options = append(options, slack.MsgOptionBlocks(blocks...))
+	}
+
+	return options
+}
+
+// slackMessage returns the colored attachment and the blocks of a message, with an
+// optional note under its title. Messages of checks show their details as block
+// fields, and the body in the attachment.
+func slackMessage(msg *Message, note string) (slack.Attachment, []slack.Block) {
+	title := msg.Title
+	if note != "" {
+		title = fmt.Sprintf("%s \n %s", msg.Title, note)
+	}
+
 	attachment := slack.Attachment{
-		Color: color,
-		Text:  text,
+		Color:    messageColor(msg.Type),
+		Fallback: msg.Title,
 	}
-	channelId, timestamp, err := api.PostMessage(
-		s.Channel,
-		slack.MsgOptionPostMessageParameters(params),
-		slack.MsgOptionAttachments(attachment))
-	if err != nil {
-		return err
+
+	facts := messageFacts(msg)
+	if len(facts) == 0 {
+		attachment.Text = fmt.Sprintf("%s \n %s", title, msg.Body)
+		return attachment, nil
 	}
 
-	log.Debug().Msgf("Message successfully sent to channel '%s' at '%s'", channelId, timestamp)
-	return nil
+	var fields []*slack.TextBlockObject
+	for _, f := range facts {
+		value := f.Value
+		if f.Name == "Runbook" {
+			value = fmt.Sprintf("<%s|%s>", f.Value, f.Value)
+		}
+		fields = append(fields, slack.NewTextBlockObject(slack.MarkdownType,
+			fmt.Sprintf("*%s*\n%s", f.Name, value), false, false))
+	}
+	if len(fields) > slackMaxFields {
+		fields = fields[:slackMaxFields]
+	}
+
+	heading := "*" + msg.Title + "*"
+	if note != "" {
+		heading += "\n" + note
+	}
+
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, heading, false, false), nil, nil),
+		slack.NewSectionBlock(nil, fields, nil),
+	}
+
+	var labels []slack.MixedElement
+	for _, f := range labelFacts(msg) {
+		labels = append(labels, slack.NewTextBlockObject(slack.PlainTextType, f.Name+": "+f.Value, false, false))
+	}
+	if len(labels) > slackMaxElements {
+		labels = labels[:slackMaxElements]
+	}
+	if len(labels) > 0 {
+		blocks = append(blocks, slack.NewContextBlock("", labels...))
+	}
+
+	if msg.Body != "" {
+		attachment.Text = "```" + msg.Body + "```"
+	}
+
+	return attachment, blocks
+}
+
+// thread returns the message posted by this handler for the incident of a message
+func (s *slackHandler) thread(msg *Message) *SlackMessage {
+	if msg.Incident == nil || msg.Incident.SlackMessages == nil {
+		return nil
+	}
+	return msg.Incident.SlackMessages[handlerKey(s.Name)]
+}
+
+// formatMinutes formats a duration as a number of minutes
+func formatMinutes(d time.Duration) string {
+	minutes := int(math.Round(d.Minutes()))
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
 }
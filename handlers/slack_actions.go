@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nlopes/slack"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/uberwachen/storage"
+)
+
+const (
+	SlackActionAcknowledge = "acknowledge"
+	SlackActionSilence     = "silence"
+	SlackActionResolve     = "resolve"
+)
+
+// slackSilenceDuration is how long the silence button silences an incident
+const slackSilenceDuration = time.Hour
+
+// slackActions returns the buttons added to the message of a new incident
+func slackActions(incident *Incident) []slack.AttachmentAction {
+	return []slack.AttachmentAction{
+		{Name: SlackActionAcknowledge, Text: "Acknowledge", Type: "button", Value: incident.Name},
+		{Name: SlackActionSilence, Text: "Silence 1h", Type: "button", Value: incident.Name},
+		{Name: SlackActionResolve, Text: "Resolve", Type: "button", Value: incident.Name, Style: "danger"},
+	}
+}
+
+// HandleSlackAction applies the action of a button clicked in Slack
+// to its incident and returns the message replacing the original one
+func HandleSlackAction(callback *slack.InteractionCallback) (*slack.Message, error) {
+	if len(callback.ActionCallback.AttachmentActions) == 0 {
+		return nil, errors.New("no action in Slack payload")
+	}
+	if len(callback.OriginalMessage.Attachments) == 0 {
+		return nil, errors.New("no attachment in original Slack message")
+	}
+
+	action := callback.ActionCallback.AttachmentActions[0]
+	msg := callback.OriginalMessage
+	msg.ReplaceOriginal = true
+	attachment := &msg.Attachments[0]
+	user := fmt.Sprintf("<@%s>", callback.User.ID)
+
+	incident, err := getIncident(action.Value)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error getting incident '%s': %v", action.Value, err))
+	}
+
+	if incident.ID == "" || incident.ID != callback.CallbackID {
+		attachment.Actions = nil
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+			Title: "Closed",
+			Value: fmt.Sprintf("Incident '%s' is no longer open", callback.CallbackID),
+		})
+		return &msg, nil
+	}
+
+	var field slack.AttachmentField
+	switch action.Name {
+	case SlackActionAcknowledge:
+		err = incident.Acknowledge(callback.User.Name)
+		field = slack.AttachmentField{Title: "Acknowledged", Value: "by " + user, Short: true}
+	case SlackActionSilence:
+		err = incident.Silence(callback.User.Name, slackSilenceDuration)
+		field = slack.AttachmentField{
+			Title: "Silenced",
+			Value: fmt.Sprintf("by %s until %s", user, incident.SilencedUntil.Format("15:04 MST")),
+			Short: true,
+		}
+	case SlackActionResolve:
+		err = incident.Resolve(callback.User.Name)
+		field = slack.AttachmentField{
+			Title: "Resolved",
+			Value: fmt.Sprintf("by %s, not notified again until the check passes", user),
+			Short: true,
+		}
+		attachment.Color = messageColor(MsgTypeResolve)
+	default:
+		return nil, errors.New(fmt.Sprintf("unknown Slack action '%s'", action.Name))
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Error applying action '%s' to incident '%s': %v",
+			action.Name, incident.ID, err))
+	}
+
+	actions := []slack.AttachmentAction{}
+	if action.Name != SlackActionResolve {
+		for _, a := range attachment.Actions {
+			if a.Name != action.Name {
+				actions = append(actions, a)
+			}
+		}
+	}
+	attachment.Actions = actions
+	attachment.Fields = append(attachment.Fields, field)
+
+	return &msg, nil
+}
+
+// getIncident gets the open incident of a check
+func getIncident(name string) (*Incident, error) {
+	db := viper.Get("storage").(storage.Storage)
+	incident := &Incident{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := db.Get(ctx, name, incident)
+	if err != nil {
+		return nil, err
+	}
+
+	return incident, nil
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/nlopes/slack"
+)
+
+func slackCallback(action string, incident *Incident) *slack.InteractionCallback {
+	callback := &slack.InteractionCallback{
+		CallbackID: incident.ID,
+		User:       slack.User{ID: "U1", Name: "alice"},
+	}
+	callback.ActionCallback.AttachmentActions = []*slack.AttachmentAction{{Name: action, Value: incident.Name}}
+	callback.OriginalMessage.Attachments = []slack.Attachment{{Actions: slackActions(incident)}}
+	return callback
+}
+
+func TestHandleSlackAction(t *testing.T) {
+	tests := []struct {
+		action  string
+		field   string
+		actions int
+		check   func(i *Incident) bool
+	}{
+		{SlackActionAcknowledge, "Acknowledged", 2, func(i *Incident) bool { return i.AckedBy == "alice" }},
+		{SlackActionSilence, "Silenced", 2, func(i *Incident) bool { return i.SilencedBy == "alice" }},
+		{SlackActionResolve, "Resolved", 0, func(i *Incident) bool { return i.ResolvedBy == "alice" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			store.reset()
+			c := NewCheck()
+			c.Name = "disk"
+			incident := NewIncident(c)
+			if err := store.Set(context.Background(), incident); err != nil {
+				t.Fatal(err)
+			}
+
+			msg, err := HandleSlackAction(slackCallback(tt.action, incident))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			attachment := msg.Attachments[0]
+			if len(attachment.Actions) != tt.actions {
+				t.Errorf("expected %d buttons left, got %d", tt.actions, len(attachment.Actions))
+			}
+			if len(attachment.Fields) != 1 || attachment.Fields[0].Title != tt.field ||
+				!strings.Contains(attachment.Fields[0].Value, "<@U1>") {
+				t.Errorf("unexpected fields %+v", attachment.Fields)
+			}
+
+			saved := store.incident(t, "disk")
+			if saved == nil {
+				t.Fatal("incident deleted")
+			}
+			if !tt.check(saved) || !saved.Silenced() {
+				t.Errorf("action not saved on incident %+v", saved)
+			}
+		})
+	}
+}
+
+func TestHandleSlackActionClosedIncident(t *testing.T) {
+	store.reset()
+	c := NewCheck()
+	c.Name = "disk"
+	incident := NewIncident(c)
+
+	msg, err := HandleSlackAction(slackCallback(SlackActionAcknowledge, incident))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attachment := msg.Attachments[0]
+	if attachment.Actions != nil || len(attachment.Fields) != 1 || attachment.Fields[0].Title != "Closed" {
+		t.Errorf("expected the incident to be closed, got %+v", attachment)
+	}
+}
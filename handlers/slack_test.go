@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// slackStub is a Slack Web API recording the calls it receives
+type slackStub struct {
+	*httptest.Server
+	mu    sync.Mutex
+	calls []slackCall
+}
+
+type slackCall struct {
+	method string
+	form   map[string]string
+}
+
+func newSlackStub(t *testing.T) *slackStub {
+	s := &slackStub{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+
+		form := map[string]string{}
+		for k := range r.PostForm {
+			form[k] = r.PostForm.Get(k)
+		}
+
+		s.mu.Lock()
+		s.calls = append(s.calls, slackCall{method: r.URL.Path[1:], form: form})
+		ts := fmt.Sprintf("1000.%04d", len(s.calls))
+		s.mu.Unlock()
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "channel": "C1", "ts": ts})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *slackStub) Calls() []slackCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]slackCall(nil), s.calls...)
+}
+
+func TestSlackHandlerThread(t *testing.T) {
+	store.reset()
+	stub := newSlackStub(t)
+
+	h := NewSlackHandler("#alerts", "token", "", "", stub.URL, false)
+	h.Name = "slack.ops"
+
+	c := NewCheck()
+	c.Name = "disk"
+	c.Status = StatusCritical
+	incident := NewIncident(c)
+	if err := store.Set(context.Background(), incident); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.Handler.Send(NewMessage(MsgTypeNew, c, incident)); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := store.incident(t, "disk")
+	m, ok := saved.SlackMessages["slack%2Eops"]
+	if !ok || m.Channel != "C1" || m.Timestamp != "1000.0001" {
+		t.Fatalf("Slack message not saved under an escaped key: %+v", saved.SlackMessages)
+	}
+
+	if err := h.Handler.Send(NewMessage(MsgTypeUpdate, c, saved)); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Status = StatusOK
+	if err := h.Handler.Send(NewMessage(MsgTypeResolve, c, saved)); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := stub.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(calls))
+	}
+	if calls[0].method != "chat.postMessage" || calls[0].form["channel"] != "#alerts" {
+		t.Errorf("unexpected new message call %+v", calls[0])
+	}
+	if calls[1].method != "chat.postMessage" || calls[1].form["thread_ts"] != "1000.0001" {
+		t.Errorf("update not posted in the thread: %+v", calls[1])
+	}
+	if calls[2].method != "chat.update" || calls[2].form["ts"] != "1000.0001" || calls[2].form["channel"] != "C1" {
+		t.Errorf("resolve didn't update the message: %+v", calls[2])
+	}
+}
+
+func TestSlackHandlerResolveWithoutThread(t *testing.T) {
+	store.reset()
+	stub := newSlackStub(t)
+
+	h := NewSlackHandler("#alerts", "token", "", "", stub.URL, false)
+	h.Name = "slack"
+
+	c := NewCheck()
+	c.Name = "disk"
+	if err := h.Handler.Send(NewMessage(MsgTypeResolve, c, NewIncident(c))); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := stub.Calls()
+	if len(calls) != 1 || calls[0].method != "chat.postMessage" || calls[0].form["thread_ts"] != "" {
+		t.Errorf("expected a new message, got %+v", calls)
+	}
+}
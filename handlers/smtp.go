@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/uberwachen/util"
+)
+
+// defaultSMTPTimeout is used when no timeout is configured
+const defaultSMTPTimeout = 30 * time.Second
+
+const (
+	SMTPTLSStartTLS = "starttls"
+	SMTPTLSImplicit = "implicit"
+	SMTPTLSNone     = "none"
+
+	SMTPAuthPlain = "plain"
+	SMTPAuthLogin = "login"
+	SMTPAuthNone  = "none"
+)
+
+// smtpHandler represents an SMTP email handler
+type smtpHandler struct {
+	*Handler
+	Host               string   `json:"host"`
+	Port               int      `json:"port"`
+	TLS                string   `json:"tls"`
+	InsecureSkipVerify bool     `json:"insecure_skip_verify" bson:"insecure_skip_verify"`
+	Auth               string   `json:"auth"`
+	Username           string   `json:"username"`
+	Password           string   `json:"-" bson:"-"`
+	SubjectPrefix      string   `json:"subject_prefix" bson:"subject_prefix"`
+	From               string   `json:"from"`
+	FromName           string   `json:"from_name" bson:"from_name"`
+	To                 []string `json:"to"`
+	Cc                 []string `json:"cc"`
+	timeout            time.Duration
+	from               *mail.Address
+	to                 []*mail.Address
+	cc                 []*mail.Address
+}
+
+// SMTPConfig holds the configuration of an SMTP handler
+type SMTPConfig struct {
+	Host               string        `mapstructure:"host" validate:"required"`
+	Port               int           `mapstructure:"port"`
+	TLS                string        `mapstructure:"tls"`
+	InsecureSkipVerify bool          `mapstructure:"insecureSkipVerify"`
+	Auth               string        `mapstructure:"auth"`
+	Username           string        `mapstructure:"username"`
+	Password           string        `mapstructure:"password"`
+	SubjectPrefix      string        `mapstructure:"subjectPrefix"`
+	From               string        `mapstructure:"from" validate:"required"`
+	FromName           string        `mapstructure:"fromName"`
+	To                 []string      `mapstructure:"to" validate:"required"`
+	Cc                 []string      `mapstructure:"cc"`
+	Timeout            time.Duration `mapstructure:"timeout"`
+}
+
+// Validate checks the TLS mode, the authentication and the addresses
+func (c *SMTPConfig) Validate() error {
+	var errs util.Errors
+
+	switch c.TLS {
+	case "", SMTPTLSStartTLS, SMTPTLSImplicit, SMTPTLSNone:
+	default:
+		errs = append(errs, errors.New(fmt.Sprintf("key 'tls': must be one of '%s', '%s' or '%s'",
+			SMTPTLSStartTLS, SMTPTLSImplicit, SMTPTLSNone)))
+	}
+
+	switch c.Auth {
+	case "", SMTPAuthNone:
+	case SMTPAuthPlain, SMTPAuthLogin:
+		if c.Username == "" {
+			errs = append(errs, errors.New(fmt.Sprintf("key 'username' is required with '%s' auth", c.Auth)))
+		}
+	default:
+		errs = append(errs, errors.New(fmt.Sprintf("key 'auth': must be one of '%s', '%s' or '%s'",
+			SMTPAuthPlain, SMTPAuthLogin, SMTPAuthNone)))
+	}
+
+	addresses := map[string][]string{"from": {c.From}, "to": c.To, "cc": c.Cc}
+	for _, key := range []string{"from", "to", "cc"} {
+		for _, address := range addresses[key] {
+			if address == "" {
+				continue
+			}
+			if _, err := mail.ParseAddress(address); err != nil {
+				errs = append(errs, errors.New(fmt.Sprintf("key '%s': invalid address '%s': %v", key, address, err)))
+			}
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "smtp",
+		Config: func() interface{} { return &SMTPConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewSMTPHandler(config.(*SMTPConfig))
+		},
+	})
+}
+
+// NewSMTPHandler creates an smtpHandler instance
+func NewSMTPHandler(config *SMTPConfig) (*Handler, error) {
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid address '%s': %v", config.From, err))
+	}
+
+	to, err := parseAddresses(config.To)
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := parseAddresses(config.Cc)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.FromName != "" {
+		from.Name = config.FromName
+	}
+
+	s := &smtpHandler{
+		Host:               config.Host,
+		Port:               config.Port,
+		TLS:                config.TLS,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		Auth:               config.Auth,
+		Username:           config.Username,
+		Password:           config.Password,
+		SubjectPrefix:      config.SubjectPrefix,
+		From:               config.From,
+		FromName:           config.FromName,
+		To:                 config.To,
+		Cc:                 config.Cc,
+		timeout:            config.Timeout,
+		from:               from,
+		to:                 to,
+		cc:                 cc,
+	}
+
+	if s.TLS == "" {
+		s.TLS = SMTPTLSStartTLS
+	}
+
+	if s.Auth == "" {
+		s.Auth = SMTPAuthNone
+		if s.Username != "" {
+			s.Auth = SMTPAuthPlain
+		}
+	}
+
+	if s.Port == 0 {
+		s.Port = 587
+		if s.TLS == SMTPTLSImplicit {
+			s.Port = 465
+		}
+	}
+
+	if s.timeout == 0 {
+		s.timeout = defaultSMTPTimeout
+	}
+
+	return &Handler{
+		Type:    "smtp",
+		Handler: s,
+	}, nil
+}
+
+// parseAddresses parses a list of addresses, which may have display names
+func parseAddresses(list []string) ([]*mail.Address, error) {
+	var addresses []*mail.Address
+	for _, address := range list {
+		a, err := mail.ParseAddress(address)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid address '%s': %v", address, err))
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, nil
+}
+
+// joinAddresses formats a list of addresses for an email header
+func joinAddresses(addresses []*mail.Address) string {
+	var list []string
+	for _, a := range addresses {
+		list = append(list, a.String())
+	}
+	return strings.Join(list, ", ")
+}
+
+// Send sends an email through the SMTP relay
+func (s *smtpHandler) Send(msg *Message) error {
+	body, err := s.message(msg)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	tlsConfig := &tls.Config{ServerName: s.Host, InsecureSkipVerify: s.InsecureSkipVerify}
+	dialer := &net.Dialer{Timeout: s.timeout}
+
+	var conn net.Conn
+	if s.TLS == SMTPTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = conn.SetDeadline(time.Now().Add(s.timeout))
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.TLS == SMTPTLSStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New(fmt.Sprintf("SMTP server '%s' does not support STARTTLS", addr))
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	var auth smtp.Auth
+	switch s.Auth {
+	case SMTPAuthPlain:
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	case SMTPAuthLogin:
+		auth = &loginAuth{host: s.Host, username: s.Username, password: s.Password}
+	}
+
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	// MAIL and RCPT only take the bare addresses, the display
+	// names are only used in the headers of the message
+	if err := c.Mail(s.from.Address); err != nil {
+		return err
+	}
+
+	for _, rcpt := range append(append([]*mail.Address{}, s.to...), s.cc...) {
+		if err := c.Rcpt(rcpt.Address); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Email successfully sent through '%s' to '%s'", addr, strings.Join(s.To, ", "))
+	return c.Quit()
+}
+
+// message builds a multipart email with both a text and an HTML body
+func (s *smtpHandler) message(msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	subject := strings.TrimSpace(fmt.Sprintf("%s %s", s.SubjectPrefix, msg.Title))
+	headers := []string{
+		"From: " + s.from.String(),
+		"To: " + joinAddresses(s.to),
+	}
+	if len(s.cc) > 0 {
+		headers = append(headers, "Cc: "+joinAddresses(s.cc))
+	}
+	headers = append(headers,
+		"Subject: "+mime.QEncoding.Encode("utf-8", subject),
+		"Date: "+time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q", mw.Boundary()),
+	)
+
+	buf.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	text := fmt.Sprintf("%s\n\n%s\n", msg.Title, msg.Body)
+	htmlBody, err := renderEmail(emailTemplate, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", htmlBody},
+	}
+
+	for _, part := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// loginAuth implements the LOGIN authentication mechanism
+type loginAuth struct {
+	host     string
+	username string
+	password string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Like smtp.PlainAuth, only send credentials over TLS or to localhost
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(string(fromServer))) {
+	case "username:":
+		return []byte(a.username), nil
+	case "password:":
+		return []byte(a.password), nil
+	default:
+		return nil, errors.New(fmt.Sprintf("unexpected server challenge '%s'", fromServer))
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
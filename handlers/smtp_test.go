@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpStub is a local SMTP server recording the commands and the data it receives
+type smtpStub struct {
+	listener net.Listener
+	commands []string
+	data     string
+	done     chan struct{}
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &smtpStub{listener: l, done: make(chan struct{})}
+	go s.serve()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *smtpStub) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.commands = append(s.commands, line)
+
+		switch strings.ToUpper(strings.SplitN(line, " ", 2)[0]) {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.data = data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestSMTPHandlerSend(t *testing.T) {
+	stub := newSMTPStub(t)
+
+	h, err := NewSMTPHandler(&SMTPConfig{
+		Host:          "127.0.0.1",
+		Port:          stub.port(),
+		TLS:           SMTPTLSNone,
+		SubjectPrefix: "[uberwachen]",
+		From:          "Ops <ops@example.com>",
+		To:            []string{"Alice <alice@example.com>", "bob@example.com"},
+		Cc:            []string{"carol@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = h.Handler.Send(&Message{Type: MsgTypeNew, Title: "Check failed", Body: "output"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-stub.done
+
+	expected := []string{
+		"MAIL FROM:<ops@example.com>",
+		"RCPT TO:<alice@example.com>",
+		"RCPT TO:<bob@example.com>",
+		"RCPT TO:<carol@example.com>",
+	}
+	for _, cmd := range expected {
+		if !stringInSlice(cmd, stub.commands) {
+			t.Errorf("command '%s' not sent, got %v", cmd, stub.commands)
+		}
+	}
+
+	headers := []string{
+		`From: "Ops" <ops@example.com>`,
+		`To: "Alice" <alice@example.com>, <bob@example.com>`,
+		"Cc: <carol@example.com>",
+		"Subject: [uberwachen] Check failed",
+	}
+	for _, header := range headers {
+		if !strings.Contains(stub.data, header+"\r\n") {
+			t.Errorf("header '%s' not found in:\n%s", header, stub.data)
+		}
+	}
+}
+
+func TestSMTPHandlerFromName(t *testing.T) {
+	h, err := NewSMTPHandler(&SMTPConfig{
+		Host:     "localhost",
+		From:     "ops@example.com",
+		FromName: "Uberwachen",
+		To:       []string{"alice@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := h.Handler.(*smtpHandler)
+	if s.from.String() != `"Uberwachen" <ops@example.com>` {
+		t.Errorf("unexpected from '%s'", s.from)
+	}
+	if s.Port != 587 || s.TLS != SMTPTLSStartTLS || s.Auth != SMTPAuthNone {
+		t.Errorf("unexpected defaults: port %d tls '%s' auth '%s'", s.Port, s.TLS, s.Auth)
+	}
+}
+
+func TestSMTPConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config SMTPConfig
+		errs   []string
+	}{
+		{"valid", SMTPConfig{From: "Ops <ops@example.com>", To: []string{"a@example.com"}}, nil},
+		{"bad tls", SMTPConfig{TLS: "ssl", From: "ops@example.com"}, []string{"key 'tls'"}},
+		{"auth without username", SMTPConfig{Auth: SMTPAuthLogin, From: "ops@example.com"}, []string{"key 'username'"}},
+		{"bad auth", SMTPConfig{Auth: "md5", From: "ops@example.com"}, []string{"key 'auth'"}},
+		{"bad addresses", SMTPConfig{From: "ops", To: []string{"a@example.com", "b"}}, []string{"key 'from'", "key 'to'"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errs == nil {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %v", tt.errs)
+			}
+			for _, e := range tt.errs {
+				if !strings.Contains(err.Error(), e) {
+					t.Errorf("error '%v' doesn't mention %s", err, e)
+				}
+			}
+		})
+	}
+}
+
+func TestNewSMTPHandlerInvalidAddress(t *testing.T) {
+	_, err := NewSMTPHandler(&SMTPConfig{Host: "localhost", From: "ops@example.com", To: []string{"nope"}})
+	if err == nil || !strings.Contains(err.Error(), "invalid address 'nope'") {
+		t.Errorf("expected invalid address error, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/uberwachen/storage"
+)
+
+// memStorage is an in-memory storage.Storage keeping its documents as BSON like MongoDB does
+type memStorage struct {
+	mu          sync.Mutex
+	incidents   map[string]bson.M
+	deadLetters map[string]bson.M
+	deliveries  []bson.M
+}
+
+var store = &memStorage{}
+
+// TestMain sets the storage and dispatcher once, as the dispatcher workers keep reading them
+func TestMain(m *testing.M) {
+	store.reset()
+	viper.Set("storage", storage.Storage(store))
+	viper.Set("dispatcher", NewDispatcher(100, 1))
+	os.Exit(m.Run())
+}
+
+func (s *memStorage) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.incidents = make(map[string]bson.M)
+	s.deadLetters = make(map[string]bson.M)
+	s.deliveries = nil
+}
+
+func toDoc(v interface{}) bson.M {
+	b, err := bson.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	doc := bson.M{}
+	if err := bson.Unmarshal(b, &doc); err != nil {
+		panic(err)
+	}
+	return doc
+}
+
+func fromDoc(doc interface{}, v interface{}) error {
+	b, err := bson.Marshal(bson.M{"v": doc})
+	if err != nil {
+		return err
+	}
+	var wrapper struct {
+		V bson.RawValue `bson:"v"`
+	}
+	if err := bson.Unmarshal(b, &wrapper); err != nil {
+		return err
+	}
+	return wrapper.V.Unmarshal(v)
+}
+
+func (s *memStorage) Init(ctx context.Context) error { return nil }
+
+func (s *memStorage) Get(ctx context.Context, name string, item interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range s.incidents {
+		if doc["name"] == name {
+			return fromDoc(doc, item)
+		}
+	}
+	return nil
+}
+
+func (s *memStorage) GetByID(ctx context.Context, id string, item interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doc, ok := s.incidents[id]; ok {
+		return fromDoc(doc, item)
+	}
+	return nil
+}
+
+func (s *memStorage) GetAll(ctx context.Context, items interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	docs := bson.A{}
+	for _, doc := range s.incidents {
+		docs = append(docs, doc)
+	}
+	return fromDoc(docs, items)
+}
+
+func (s *memStorage) Set(ctx context.Context, data interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc := toDoc(data)
+	s.incidents[doc["_id"].(string)] = doc
+	return nil
+}
+
+func (s *memStorage) Delete(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, doc := range s.incidents {
+		if doc["name"] == name {
+			delete(s.incidents, id)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *memStorage) Update(ctx context.Context, name string, data interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range s.incidents {
+		if doc["name"] == name {
+			for k, v := range toDoc(data) {
+				doc[k] = v
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *memStorage) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.incidents[id]
+	if !ok {
+		return nil
+	}
+
+	for path, v := range fields {
+		keys := strings.Split(path, ".")
+		d := doc
+		for _, k := range keys[:len(keys)-1] {
+			next, ok := d[k].(bson.M)
+			if !ok {
+				next = bson.M{}
+				d[k] = next
+			}
+			d = next
+		}
+		d[keys[len(keys)-1]] = toDoc(bson.M{"v": v})["v"]
+	}
+	return nil
+}
+
+func (s *memStorage) GetDeadLetter(ctx context.Context, id string, item interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doc, ok := s.deadLetters[id]; ok {
+		return fromDoc(doc, item)
+	}
+	return nil
+}
+
+func (s *memStorage) GetAllDeadLetters(ctx context.Context, items interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	docs := bson.A{}
+	for _, doc := range s.deadLetters {
+		docs = append(docs, doc)
+	}
+	return fromDoc(docs, items)
+}
+
+func (s *memStorage) SetDeadLetter(ctx context.Context, data interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc := toDoc(data)
+	s.deadLetters[doc["_id"].(string)] = doc
+	return nil
+}
+
+func (s *memStorage) DeleteDeadLetter(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.deadLetters, id)
+	return nil
+}
+
+func (s *memStorage) GetDeliveries(ctx context.Context, incidentIDs []string, items interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	docs := bson.A{}
+	for _, doc := range s.deliveries {
+		for _, id := range incidentIDs {
+			if doc["incident_id"] == id {
+				docs = append(docs, doc)
+			}
+		}
+	}
+	return fromDoc(docs, items)
+}
+
+func (s *memStorage) SetDelivery(ctx context.Context, data interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deliveries = append(s.deliveries, toDoc(data))
+	return nil
+}
+
+// incident returns the incident of a check saved in the storage
+func (s *memStorage) incident(t *testing.T, name string) *Incident {
+	t.Helper()
+
+	incident := &Incident{}
+	if err := s.Get(context.Background(), name, incident); err != nil {
+		t.Fatal(err)
+	}
+	if incident.ID == "" {
+		return nil
+	}
+	return incident
+}
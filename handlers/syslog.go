@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/uberwachen/util"
+)
+
+// syslogFacilities maps facility names to their RFC 5424 codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+const (
+	// defaultSyslogTimeout is used when no timeout is configured
+	defaultSyslogTimeout = 5 * time.Second
+
+	// defaultSyslogSocket is the local syslog socket
+	defaultSyslogSocket = "/dev/log"
+)
+
+const (
+	syslogCritical = 2
+	syslogError    = 3
+	syslogWarning  = 4
+	syslogNotice   = 5
+	syslogInfo     = 6
+)
+
+// syslogHandler represents an RFC 5424 syslog handler
+type syslogHandler struct {
+	*Handler
+	Network  string `json:"network"`
+	Address  string `json:"address"`
+	Facility string `json:"facility"`
+	AppName  string `json:"app_name" bson:"app_name"`
+	Hostname string `json:"hostname"`
+	timeout  time.Duration
+}
+
+// SyslogConfig holds the configuration of a syslog handler
+type SyslogConfig struct {
+	Network  string        `mapstructure:"network"`
+	Address  string        `mapstructure:"address"`
+	Facility string        `mapstructure:"facility"`
+	AppName  string        `mapstructure:"appName"`
+	Hostname string        `mapstructure:"hostname"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// Validate checks the network and facility are supported
+func (c *SyslogConfig) Validate() error {
+	var errs util.Errors
+
+	switch c.Network {
+	case "", "udp", "tcp", "unix", "unixgram":
+	default:
+		errs = append(errs, errors.New("key 'network': must be one of 'udp', 'tcp', 'unix' or 'unixgram'"))
+	}
+
+	if _, ok := syslogFacilities[c.Facility]; c.Facility != "" && !ok {
+		errs = append(errs, errors.New(fmt.Sprintf("key 'facility': unknown facility '%s'", c.Facility)))
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "syslog",
+		Config: func() interface{} { return &SyslogConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewSyslogHandler(config.(*SyslogConfig)), nil
+		},
+	})
+}
+
+// NewSyslogHandler creates a syslogHandler instance
+func NewSyslogHandler(config *SyslogConfig) *Handler {
+	s := &syslogHandler{
+		Network:  config.Network,
+		Address:  config.Address,
+		Facility: config.Facility,
+		AppName:  config.AppName,
+		Hostname: config.Hostname,
+		timeout:  config.Timeout,
+	}
+
+	if s.Network == "" {
+		s.Network = "udp"
+	}
+
+	if s.Address == "" {
+		if strings.HasPrefix(s.Network, "unix") {
+			s.Address = defaultSyslogSocket
+		} else {
+			s.Address = "localhost:514"
+		}
+	}
+
+	// The local syslog socket is a datagram socket
+	if s.Network == "unix" && s.Address == defaultSyslogSocket {
+		s.Network = "unixgram"
+	}
+
+	if s.Facility == "" {
+		s.Facility = "daemon"
+	}
+
+	if s.AppName == "" {
+		s.AppName = "uberwachen"
+	}
+
+	if s.Hostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "-"
+		}
+		s.Hostname = hostname
+	}
+
+	if s.timeout == 0 {
+		s.timeout = defaultSyslogTimeout
+	}
+
+	return &Handler{
+		Type:    "syslog",
+		Handler: s,
+	}
+}
+
+// Send writes the message to the syslog server
+func (s *syslogHandler) Send(msg *Message) error {
+	conn, err := net.DialTimeout(s.Network, s.Address, s.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(s.timeout))
+	if err != nil {
+		return err
+	}
+
+	line := s.format(msg, time.Now())
+	if s.Network == "tcp" {
+		// RFC 6587 octet counting framing
+		line = fmt.Sprintf("%d %s", len(line), line)
+	}
+
+	_, err = conn.Write([]byte(line))
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Message successfully sent to syslog '%s://%s'", s.Network, s.Address)
+	return nil
+}
+
+// format returns the message as an RFC 5424 syslog message
+func (s *syslogHandler) format(msg *Message, t time.Time) string {
+	pri := syslogFacilities[s.Facility]*8 + syslogSeverity(msg)
+
+	var params []string
+	params = append(params, fmt.Sprintf(`type="%s"`, sdEscape(msg.Type)))
+	if msg.Check != nil {
+		params = append(params, fmt.Sprintf(`check="%s"`, sdEscape(msg.Check.Name)))
+		params = append(params, fmt.Sprintf(`status="%s"`, StatusName(msg.Check.Status)))
+	}
+	if msg.Incident != nil {
+		params = append(params, fmt.Sprintf(`incident="%s"`, sdEscape(msg.Incident.ID)))
+	}
+
+	text := strings.ReplaceAll(fmt.Sprintf("%s: %s", msg.Title, msg.Body), "\n", " ")
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s [uberwachen@32473 %s] %s",
+		pri, t.UTC().Format(time.RFC3339Nano), s.Hostname, s.AppName, os.Getpid(),
+		msg.Type, strings.Join(params, " "), text)
+}
+
+// syslogSeverity maps a message to a syslog severity
+func syslogSeverity(msg *Message) int {
+	if msg.Type == MsgTypeResolve {
+		return syslogNotice
+	}
+
+	if msg.Check == nil {
+		return syslogInfo
+	}
+
+	switch msg.Check.Status {
+	case StatusOK:
+		return syslogNotice
+	case StatusWarning:
+		return syslogWarning
+	case StatusCritical:
+		return syslogCritical
+	default:
+		return syslogError
+	}
+}
+
+// sdEscape escapes a structured data parameter value
+func sdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSyslogHandlerDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  SyslogConfig
+		network string
+		address string
+	}{
+		{"udp", SyslogConfig{}, "udp", "localhost:514"},
+		{"tcp", SyslogConfig{Network: "tcp", Address: "logs:6514"}, "tcp", "logs:6514"},
+		{"unix socket", SyslogConfig{Network: "unix"}, "unixgram", "/dev/log"},
+		{"unix stream", SyslogConfig{Network: "unix", Address: "/run/syslog.sock"}, "unix", "/run/syslog.sock"},
+		{"unixgram", SyslogConfig{Network: "unixgram"}, "unixgram", "/dev/log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSyslogHandler(&tt.config).Handler.(*syslogHandler)
+			if s.Network != tt.network || s.Address != tt.address {
+				t.Errorf("expected %s://%s, got %s://%s", tt.network, tt.address, s.Network, s.Address)
+			}
+		})
+	}
+}
+
+func TestSyslogHandlerFormat(t *testing.T) {
+	s := NewSyslogHandler(&SyslogConfig{Facility: "local0", Hostname: "host"}).Handler.(*syslogHandler)
+
+	c := NewCheck()
+	c.Name = `disk "root"`
+	c.Status = StatusCritical
+	incident := NewIncident(c)
+	msg := &Message{Type: MsgTypeNew, Title: "title", Body: "line 1\nline 2", Check: c, Incident: incident}
+
+	line := s.format(msg, time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	// local0 (16) * 8 + critical (2)
+	if !strings.HasPrefix(line, "<130>1 2022-01-02T03:04:05Z host uberwachen ") {
+		t.Errorf("unexpected header: %s", line)
+	}
+	if !strings.Contains(line, `check="disk \"root\"" status="CRITICAL" incident="`+incident.ID+`"`) {
+		t.Errorf("unexpected structured data: %s", line)
+	}
+	if !strings.HasSuffix(line, "title: line 1 line 2") {
+		t.Errorf("unexpected text: %s", line)
+	}
+}
+
+func TestSyslogHandlerSend(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	h := NewSyslogHandler(&SyslogConfig{Address: conn.LocalAddr().String()})
+	if err := h.Handler.Send(&Message{Type: MsgTypeResolve, Title: "resolved"}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// daemon (3) * 8 + notice (5)
+	if line := string(buf[:n]); !strings.HasPrefix(line, "<29>1 ") || !strings.HasSuffix(line, "resolved: ") {
+		t.Errorf("unexpected line: %s", line)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TeamsFormatMessageCard  = "messagecard"
+	TeamsFormatAdaptiveCard = "adaptivecard"
+)
+
+// teamsHandler represents a Microsoft Teams incoming webhook handler
+type teamsHandler struct {
+	*Handler
+	URL    string `json:"-" bson:"-"`
+	Format string `json:"format"`
+	client *http.Client
+}
+
+// TeamsConfig holds the configuration of a Microsoft Teams handler
+type TeamsConfig struct {
+	URL     string        `mapstructure:"url" validate:"required"`
+	Format  string        `mapstructure:"format"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// Validate checks the card format is supported
+func (c *TeamsConfig) Validate() error {
+	switch c.Format {
+	case "", TeamsFormatMessageCard, TeamsFormatAdaptiveCard:
+		return nil
+	default:
+		return errors.New(fmt.Sprintf("key 'format': must be one of '%s' or '%s'",
+			TeamsFormatMessageCard, TeamsFormatAdaptiveCard))
+	}
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "teams",
+		Config: func() interface{} { return &TeamsConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewTeamsHandler(config.(*TeamsConfig)), nil
+		},
+	})
+}
+
+// NewTeamsHandler creates a teamsHandler instance
+func NewTeamsHandler(config *TeamsConfig) *Handler {
+	t := &teamsHandler{
+		URL:    config.URL,
+		Format: config.Format,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+
+	if t.Format == "" {
+		t.Format = TeamsFormatMessageCard
+	}
+
+	if t.client.Timeout == 0 {
+		t.client.Timeout = defaultHTTPTimeout
+	}
+
+	return &Handler{
+		Type:    "teams",
+		Handler: t,
+	}
+}
+
+// Send posts a card to a Microsoft Teams channel
+func (t *teamsHandler) Send(msg *Message) error {
+	var card interface{}
+	if t.Format == TeamsFormatAdaptiveCard {
+		card = adaptiveCard(msg)
+	} else {
+		card = messageCard(msg)
+	}
+
+	b, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	_, err = doRequest(t.client, http.MethodPost, t.URL, headers, b)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msg("Message successfully sent to Microsoft Teams")
+	return nil
+}
+
+func messageCard(msg *Message) map[string]interface{} {
+	var facts []map[string]string
+	for _, f := range messageFacts(msg) {
+		facts = append(facts, map[string]string{"name": f.Name, "value": f.Value})
+	}
+
+	return map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    msg.Title,
+		"themeColor": strings.TrimPrefix(messageColor(msg.Type), "#"),
+		"title":      msg.Title,
+		"sections": []map[string]interface{}{
+			{
+				"facts": facts,
+				"text":  fmt.Sprintf("<pre>%s</pre>", html.EscapeString(msg.Body)),
+			},
+		},
+	}
+}
+
+func adaptiveCard(msg *Message) map[string]interface{} {
+	color := "Default"
+	switch msg.Type {
+	case MsgTypeNew:
+		color = "Attention"
+	case MsgTypeUpdate:
+		color = "Warning"
+	case MsgTypeResolve:
+		color = "Good"
+	}
+
+	var facts []map[string]string
+	for _, f := range messageFacts(msg) {
+		facts = append(facts, map[string]string{"title": f.Name, "value": f.Value})
+	}
+
+	body := []map[string]interface{}{
+		{
+			"type":   "TextBlock",
+			"text":   msg.Title,
+			"weight": "Bolder",
+			"size":   "Medium",
+			"color":  color,
+			"wrap":   true,
+		},
+	}
+
+	if len(facts) > 0 {
+		body = append(body, map[string]interface{}{
+			"type":  "FactSet",
+			"facts": facts,
+		})
+	}
+
+	body = append(body, map[string]interface{}{
+		"type":     "TextBlock",
+		"text":     msg.Body,
+		"fontType": "Monospace",
+		"wrap":     true,
+	})
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			},
+		},
+	}
+}
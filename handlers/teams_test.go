@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTeamsHandlerSend(t *testing.T) {
+	tests := []struct {
+		format   string
+		expected []string
+	}{
+		{"", []string{`"@type":"MessageCard"`, `"themeColor":"DF0101"`, `"name":"Check","value":"disk"`,
+			`"text":"\u003cpre\u003e\u0026lt;ok\u0026gt;\u003c/pre\u003e"`}},
+		{TeamsFormatAdaptiveCard, []string{`"type":"AdaptiveCard"`, `"color":"Attention"`,
+			`"title":"Check","value":"disk"`, `"text":"\u003cok\u003e"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var body string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var v interface{}
+				if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+					t.Errorf("invalid payload: %v", err)
+				}
+				b, _ := json.Marshal(v)
+				body = string(b)
+			}))
+			defer srv.Close()
+
+			h := NewTeamsHandler(&TeamsConfig{URL: srv.URL, Format: tt.format})
+			msg := testCheckMessage(MsgTypeNew, "disk failed", "disk", StatusCritical, "")
+			msg.Body = "<ok>"
+			if err := h.Handler.Send(msg); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, e := range tt.expected {
+				if !strings.Contains(body, e) {
+					t.Errorf("payload doesn't contain %s:\n%s", e, body)
+				}
+			}
+		})
+	}
+}
+
+func TestAdaptiveCardFacts(t *testing.T) {
+	tests := []struct {
+		msg    *Message
+		blocks int
+	}{
+		{&Message{Type: MsgTypeResolve, Title: "manual"}, 2},
+		{testCheckMessage(MsgTypeResolve, "disk passed", "disk", StatusOK, ""), 3},
+	}
+
+	for _, tt := range tests {
+		card := adaptiveCard(tt.msg)
+		content := card["attachments"].([]map[string]interface{})[0]["content"].(map[string]interface{})
+		body := content["body"].([]map[string]interface{})
+		if len(body) != tt.blocks {
+			t.Errorf("%q: expected %d blocks, got %d", tt.msg.Title, tt.blocks, len(body))
+		}
+		if body[0]["color"] != "Good" {
+			t.Errorf("%q: expected color Good, got %v", tt.msg.Title, body[0]["color"])
+		}
+	}
+}
+
+func TestTeamsConfigValidate(t *testing.T) {
+	tests := []struct {
+		format string
+		err    bool
+	}{
+		{"", false},
+		{TeamsFormatMessageCard, false},
+		{TeamsFormatAdaptiveCard, false},
+		{"card", true},
+	}
+
+	for _, tt := range tests {
+		if err := (&TeamsConfig{Format: tt.format}).Validate(); (err != nil) != tt.err {
+			t.Errorf("%q: expected error %v, got %v", tt.format, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultTelegramURL = "https://api.telegram.org"
+
+	// telegramMaxText is the maximum length of the text of a message
+	telegramMaxText = 4096
+)
+
+// telegramEscaper escapes the characters reserved by Telegram's MarkdownV2
+var telegramEscaper = strings.NewReplacer(
+	"_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)", "~", "\\~", "`", "\\`",
+	">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-", "=", "\\=", "|", "\\|", "{", "\\{", "}", "\\}",
+	".", "\\.", "!", "\\!", "\\", "\\\\",
+)
+
+// telegramCodeEscaper escapes the characters reserved inside MarkdownV2 code blocks
+var telegramCodeEscaper = strings.NewReplacer("`", "\\`", "\\", "\\\\")
+
+// telegramHandler represents a Telegram bot handler
+type telegramHandler struct {
+	*Handler
+	URL    string `json:"url"`
+	Token  string `json:"-" bson:"-"`
+	ChatID string `json:"chat_id" bson:"chat_id"`
+	client *http.Client
+}
+
+// TelegramConfig holds the configuration of a Telegram handler
+type TelegramConfig struct {
+	URL     string        `mapstructure:"url"`
+	Token   string        `mapstructure:"token" validate:"required"`
+	ChatID  string        `mapstructure:"chatId" validate:"required"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "telegram",
+		Config: func() interface{} { return &TelegramConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewTelegramHandler(config.(*TelegramConfig)), nil
+		},
+	})
+}
+
+// NewTelegramHandler creates a telegramHandler instance
+func NewTelegramHandler(config *TelegramConfig) *Handler {
+	t := &telegramHandler{
+		URL:    strings.TrimSuffix(config.URL, "/"),
+		Token:  config.Token,
+		ChatID: config.ChatID,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+
+	if t.URL == "" {
+		t.URL = defaultTelegramURL
+	}
+
+	if t.client.Timeout == 0 {
+		t.client.Timeout = defaultHTTPTimeout
+	}
+
+	return &Handler{
+		Type:    "telegram",
+		Handler: t,
+	}
+}
+
+type telegramMessage struct {
+	ChatID    string `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode"`
+}
+
+// Send sends a message to a Telegram chat
+func (t *telegramHandler) Send(msg *Message) error {
+	text := fmt.Sprintf("*%s*", escapeTruncate(telegramEscaper, msg.Title, telegramMaxText-2))
+	if msg.Body != "" {
+		n := telegramMaxText - utf8.RuneCountInString(text) - utf8.RuneCountInString("\n```\n\n```")
+		if n > 0 {
+			text += fmt.Sprintf("\n```\n%s\n```", escapeTruncate(telegramCodeEscaper, msg.Body, n))
+		}
+	}
+
+	b, err := json.Marshal(&telegramMessage{
+		ChatID:    t.ChatID,
+		Text:      text,
+		ParseMode: "MarkdownV2",
+	})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	_, err = doRequest(t.client, http.MethodPost, fmt.Sprintf("%s/bot%s/sendMessage", t.URL, t.Token), headers, b)
+	if err != nil {
+		// don't leak the bot token in the logs
+		return errors.New(strings.ReplaceAll(err.Error(), t.Token, "<token>"))
+	}
+
+	log.Debug().Msgf("Message successfully sent to Telegram chat '%s'", t.ChatID)
+	return nil
+}
+
+// escapeTruncate escapes a string, keeping the characters whose escaped form fits in n characters
+func escapeTruncate(r *strings.Replacer, s string, n int) string {
+	var b strings.Builder
+	count := 0
+	for _, c := range s {
+		e := r.Replace(string(c))
+		l := utf8.RuneCountInString(e)
+		if count+l > n {
+			break
+		}
+		b.WriteString(e)
+		count += l
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTelegramHandlerSend(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		body  string
+		text  string
+	}{
+		{"plain", "disk failed", "", "*disk failed*"},
+		{"escaped title", "Check 'disk_usage' failed (90.5%)!", "", "*Check 'disk\\_usage' failed \\(90\\.5%\\)\\!*"},
+		{"backslash", `C:\ full`, "", `*C:\\ full*`},
+		{"body", "disk failed", "use `df` in C:\\", "*disk failed*\n```\nuse \\`df\\` in C:\\\\\n```"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			received := &telegramMessage{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				_ = json.NewDecoder(r.Body).Decode(received)
+			}))
+			defer srv.Close()
+
+			h := NewTelegramHandler(&TelegramConfig{URL: srv.URL, Token: "123:abc", ChatID: "-42"})
+			if err := h.Handler.Send(&Message{Type: MsgTypeNew, Title: tt.title, Body: tt.body}); err != nil {
+				t.Fatal(err)
+			}
+
+			if path != "/bot123:abc/sendMessage" {
+				t.Errorf("unexpected path '%s'", path)
+			}
+			if received.ChatID != "-42" || received.ParseMode != "MarkdownV2" {
+				t.Errorf("unexpected message %+v", received)
+			}
+			if received.Text != tt.text {
+				t.Errorf("expected text %q, got %q", tt.text, received.Text)
+			}
+		})
+	}
+}
+
+func TestTelegramHandlerSendTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		body  string
+		end   string
+	}{
+		{"body", "disk failed", strings.Repeat("日", 5000), "日\n```"},
+		{"escaped body", "disk failed", strings.Repeat("`", 5000), "\\`\n```"},
+		{"title", strings.Repeat(".", 5000), "output", "\\.*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := &telegramMessage{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewDecoder(r.Body).Decode(received)
+			}))
+			defer srv.Close()
+
+			h := NewTelegramHandler(&TelegramConfig{URL: srv.URL, Token: "123:abc", ChatID: "-42"})
+			if err := h.Handler.Send(&Message{Type: MsgTypeNew, Title: tt.title, Body: tt.body}); err != nil {
+				t.Fatal(err)
+			}
+
+			if n := utf8.RuneCountInString(received.Text); n > telegramMaxText || n < telegramMaxText-2 {
+				t.Errorf("expected a text of at most %d characters, got %d", telegramMaxText, n)
+			}
+			if !strings.HasSuffix(received.Text, tt.end) {
+				t.Errorf("expected the text to end with %q, got %q", tt.end, received.Text[len(received.Text)-10:])
+			}
+		})
+	}
+}
+
+func TestTelegramHandlerSendErrorHidesToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	h := NewTelegramHandler(&TelegramConfig{URL: srv.URL, Token: "123:abc", ChatID: "-42"})
+	err := h.Handler.Send(&Message{Type: MsgTypeNew, Title: "disk failed"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if strings.Contains(err.Error(), "123:abc") || !strings.Contains(err.Error(), "/bot<token>/sendMessage") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	TemplateTitle = "title"
+	TemplateBody  = "body"
+)
+
+// MessageTemplate renders the title and body of a message
+type MessageTemplate struct {
+	Title *template.Template
+	Body  *template.Template
+}
+
+// Templates are the message templates of a handler by message type,
+// the empty type holding the ones used for every type
+type Templates map[string]*MessageTemplate
+
+// TemplateData is the data given to the message templates
+type TemplateData struct {
+	Type     string
+	Message  *Message
+	Check    *Check
+	Incident *Incident
+	Labels   Labels
+	History  []int
+	Perfdata []Perfdata
+}
+
+// defaultTemplates render the messages of the handlers without templates
+var defaultTemplates = Templates{
+	MsgTypeNew: {
+		Title: mustParseTemplate("new.title",
+			"Incident '{{.Incident.ID}}' started - Check '{{.Check.Name}}' failed after {{.Incident.Attempts}} attempts"),
+		Body: mustParseTemplate("new.body", "{{.Incident.Output}}"),
+	},
+	MsgTypeUpdate: {
+		Title: mustParseTemplate("update.title",
+			"Incident '{{.Incident.ID}}' updated - Check '{{.Check.Name}}' failed with a different output"),
+		Body: mustParseTemplate("update.body", "{{.Incident.Output}}"),
+	},
+	MsgTypeResolve: {
+		Title: mustParseTemplate("resolve.title", "Incident '{{.Incident.ID}}' resolved - Check '{{.Incident.Name}}' passed"),
+		Body:  mustParseTemplate("resolve.body", "{{.Check.Output}}"),
+	},
+}
+
+var templateFuncs = template.FuncMap{
+	"status": StatusName,
+	"join":   strings.Join,
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// ParseTemplate parses a message template and checks it renders with a sample
+// message, so unknown fields and failing functions are found when it's loaded.
+// Missing map keys, like labels only some checks have, render as "<no value>".
+func ParseTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	c := NewCheck()
+	c.Name = "sample"
+	c.Labels = Labels{}
+	msg := &Message{Type: MsgTypeNew, Check: c, Incident: NewIncident(c)}
+	err = t.Execute(&bytes.Buffer{}, newTemplateData(msg))
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func mustParseTemplate(name, text string) *template.Template {
+	return template.Must(template.New(name).Funcs(templateFuncs).Parse(text))
+}
+
+func newTemplateData(msg *Message) *TemplateData {
+	data := &TemplateData{
+		Type:     msg.Type,
+		Message:  msg,
+		Check:    msg.Check,
+		Incident: msg.Incident,
+		Labels:   msg.Check.Labels,
+		History:  msg.Check.History,
+		Perfdata: ParsePerfdata(msg.Check.Output),
+	}
+
+	return data
+}
+
+// lookup returns a template of a message type, falling back on
+// the one for every type and then on the default one
+func (t Templates) lookup(msgType, part string) *template.Template {
+	for _, ts := range []Templates{t, defaultTemplates} {
+		for _, k := range []string{msgType, ""} {
+			mt, ok := ts[k]
+			if !ok || mt == nil {
+				continue
+			}
+			if part == TemplateTitle && mt.Title != nil {
+				return mt.Title
+			}
+			if part == TemplateBody && mt.Body != nil {
+				return mt.Body
+			}
+		}
+	}
+	return nil
+}
+
+// render sets the title and body of a message with the templates,
+// leaving the message unchanged if either of them fails
+func (t Templates) render(msg *Message) error {
+	if msg.Check == nil || msg.Incident == nil {
+		return errors.New("message has no check or incident")
+	}
+
+	data := newTemplateData(msg)
+	title, err := t.execute(msg.Type, TemplateTitle, data, msg.Title)
+	if err != nil {
+		return err
+	}
+
+	body, err := t.execute(msg.Type, TemplateBody, data, msg.Body)
+	if err != nil {
+		return err
+	}
+
+	msg.Title = title
+	msg.Body = body
+	return nil
+}
+
+// execute renders a part of a message, returning current if there's no template for it
+func (t Templates) execute(msgType, part string, data *TemplateData, current string) (string, error) {
+	tmpl := t.lookup(msgType, part)
+	if tmpl == nil {
+		return current, nil
+	}
+
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Error rendering %s template: %v", part, err))
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		err  string
+	}{
+		{"fields", "[{{status .Check.Status}}] {{.Check.Name}} {{.Incident.ID}}", ""},
+		{"missing label", "{{.Labels.team}}", ""},
+		{"functions", "{{upper .Type}} {{json .Labels}} {{join .Check.HandlerNames \",\"}}", ""},
+		{"syntax", "{{.Check.Name", "unclosed action"},
+		{"unknown field", "{{.Check.Nope}}", "can't evaluate field Nope"},
+		{"unknown function", "{{nope .Type}}", "function \"nope\" not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTemplate(tt.name, tt.text)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error '%s', got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestTemplatesRender(t *testing.T) {
+	mustParse := func(text string) *template.Template {
+		tmpl, err := ParseTemplate("test", text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tmpl
+	}
+
+	failing := template.Must(template.New("failing").Funcs(templateFuncs).Parse("{{index .History 10}}"))
+
+	tests := []struct {
+		name      string
+		templates Templates
+		msgType   string
+		title     string
+		body      string
+		err       bool
+	}{
+		{
+			name:      "every type",
+			templates: Templates{"": {Title: mustParse("{{.Check.Name}} is {{status .Check.Status}}"), Body: mustParse("team {{.Labels.team}}")}},
+			msgType:   MsgTypeNew,
+			title:     "disk is CRITICAL",
+			body:      "team ops",
+		},
+		{
+			name:      "type over every type",
+			templates: Templates{"": {Title: mustParse("all")}, MsgTypeUpdate: {Title: mustParse("update")}},
+			msgType:   MsgTypeUpdate,
+			title:     "update",
+			body:      "disk full | used=95%;80;90",
+		},
+		{
+			name:      "default",
+			templates: Templates{MsgTypeNew: {Title: mustParse("new")}},
+			msgType:   MsgTypeResolve,
+			title:     "Incident 'i1' resolved - Check 'disk' passed",
+			body:      "disk full | used=95%;80;90",
+		},
+		{
+			name:      "failing body keeps the title",
+			templates: Templates{"": {Title: mustParse("title"), Body: failing}},
+			msgType:   MsgTypeNew,
+			title:     "original",
+			body:      "original",
+			err:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCheck()
+			c.Name = "disk"
+			c.Status = StatusCritical
+			c.Output = "disk full | used=95%;80;90"
+			c.Labels = Labels{"team": "ops"}
+			incident := NewIncident(c)
+			incident.ID = "i1"
+			msg := &Message{Type: tt.msgType, Check: c, Incident: incident, Title: "original", Body: "original"}
+
+			err := tt.templates.render(msg)
+			if (err != nil) != tt.err {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if msg.Title != tt.title || msg.Body != tt.body {
+				t.Errorf("expected '%s' '%s', got '%s' '%s'", tt.title, tt.body, msg.Title, msg.Body)
+			}
+		})
+	}
+}
+
+func TestParsePerfdata(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected []Perfdata
+	}{
+		{"OK", nil},
+		{"OK | load1=0.5;1;2;0 'free space'=20GB", []Perfdata{
+			{Label: "load1", Value: 0.5, Warn: "1", Crit: "2", Min: "0"},
+			{Label: "free space", Value: 20, Unit: "GB"},
+		}},
+		{"OK | bad=abc used=95%", []Perfdata{{Label: "used", Value: 95, Unit: "%"}}},
+		{"first line\nsecond | time=1.5s;;;0;10", []Perfdata{{Label: "time", Value: 1.5, Unit: "s", Min: "0", Max: "10"}}},
+	}
+
+	for _, tt := range tests {
+		if perfdata := ParsePerfdata(tt.output); !reflect.DeepEqual(perfdata, tt.expected) {
+			t.Errorf("ParsePerfdata(%q): expected %+v got %+v", tt.output, tt.expected, perfdata)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Constructor creates a Handler from its decoded config
+type Constructor func(config interface{}) (*Handler, error)
+
+// HandlerType describes a type of handler that can be used in the handlers definition files
+type HandlerType struct {
+	// Name is the value of the 'type' key in the handlers definition files
+	Name string
+
+	// Config returns a pointer to a new config struct the handler definition is decoded into.
+	// Fields tagged with `validate:"required"` must be set and if the struct
+	// implements Validator it gets validated after decoding.
+	Config func() interface{}
+
+	// New creates the Handler from the decoded config
+	New Constructor
+}
+
+// Validator is implemented by handler configs needing more than the required fields checks
+type Validator interface {
+	Validate() error
+}
+
+var (
+	typesMu sync.RWMutex
+	types   = make(map[string]*HandlerType)
+)
+
+// RegisterType makes a handler type available, it panics if the type is already registered
+func RegisterType(t *HandlerType) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+
+	if _, exist := types[t.Name]; exist {
+		panic(fmt.Sprintf("handler type '%s' already registered", t.Name))
+	}
+	types[t.Name] = t
+}
+
+// GetType returns a registered handler type
+func GetType(name string) (*HandlerType, error) {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+
+	if t, exist := types[name]; exist {
+		return t, nil
+	}
+	return nil, errors.New(fmt.Sprintf("unknown handler type '%s', must be one of: %v", name, typeNames()))
+}
+
+// typeNames returns the sorted names of the registered handler types
+func typeNames() []string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
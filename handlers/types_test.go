@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestGetType(t *testing.T) {
+	tests := []struct {
+		name string
+		err  string
+	}{
+		{"console", ""},
+		{"slack", ""},
+		{"sendgrid", ""},
+		{"nope", "unknown handler type 'nope', must be one of: ["},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ht, err := GetType(tt.name)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error '%s', got %v", tt.err, err)
+				}
+				if names := typeNames(); !sort.StringsAreSorted(names) || !strings.Contains(err.Error(), fmt.Sprint(names)) {
+					t.Errorf("expected the sorted types, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ht.Name != tt.name || ht.Config() == nil || ht.New == nil {
+				t.Errorf("unexpected handler type %+v", ht)
+			}
+		})
+	}
+}
+
+func TestRegisterTypeDuplicate(t *testing.T) {
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(r.(string), "handler type 'console' already registered") {
+			t.Errorf("expected a panic, got %v", r)
+		}
+	}()
+
+	RegisterType(&HandlerType{Name: "console"})
+}
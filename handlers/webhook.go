@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/uberwachen/util"
+)
+
+// webhookHandler represents a generic outbound webhook handler
+type webhookHandler struct {
+	*Handler
+	URL             string            `json:"url"`
+	Method          string            `json:"method"`
+	Headers         map[string]string `json:"-" bson:"-"`
+	Secret          string            `json:"-" bson:"-"`
+	SignatureHeader string            `json:"signature_header" bson:"signature_header"`
+	client          *http.Client
+	template        *template.Template
+}
+
+// WebhookConfig holds the configuration of a webhook handler
+type WebhookConfig struct {
+	URL             string            `mapstructure:"url" validate:"required"`
+	Method          string            `mapstructure:"method"`
+	Headers         map[string]string `mapstructure:"headers"`
+	Secret          string            `mapstructure:"secret"`
+	SignatureHeader string            `mapstructure:"signatureHeader"`
+	Timeout         time.Duration     `mapstructure:"timeout"`
+	Template        string            `mapstructure:"template"`
+}
+
+// Validate checks the method is supported and the body template parses
+func (c *WebhookConfig) Validate() error {
+	var errs util.Errors
+
+	switch strings.ToUpper(c.Method) {
+	case "", http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		errs = append(errs, errors.New(fmt.Sprintf("key 'method': unsupported method '%s'", c.Method)))
+	}
+
+	if c.Template != "" {
+		if _, err := newWebhookTemplate(c.Template); err != nil {
+			errs = append(errs, errors.New(fmt.Sprintf("key 'template': %v", err)))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func init() {
+	RegisterType(&HandlerType{
+		Name:   "webhook",
+		Config: func() interface{} { return &WebhookConfig{} },
+		New: func(config interface{}) (*Handler, error) {
+			return NewWebhookHandler(config.(*WebhookConfig))
+		},
+	})
+}
+
+// NewWebhookHandler creates a webhookHandler instance
+func NewWebhookHandler(config *WebhookConfig) (*Handler, error) {
+	w := &webhookHandler{
+		URL:             config.URL,
+		Method:          strings.ToUpper(config.Method),
+		Headers:         config.Headers,
+		Secret:          config.Secret,
+		SignatureHeader: config.SignatureHeader,
+		client:          &http.Client{Timeout: config.Timeout},
+	}
+
+	if w.Method == "" {
+		w.Method = http.MethodPost
+	}
+
+	if w.SignatureHeader == "" {
+		w.SignatureHeader = "X-Uberwachen-Signature"
+	}
+
+	if w.client.Timeout == 0 {
+		w.client.Timeout = defaultHTTPTimeout
+	}
+
+	if config.Template != "" {
+		t, err := newWebhookTemplate(config.Template)
+		if err != nil {
+			return nil, err
+		}
+		w.template = t
+	}
+
+	return &Handler{
+		Type:    "webhook",
+		Handler: w,
+	}, nil
+}
+
+func newWebhookTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(text)
+}
+
+// Send sends the message as JSON, or rendered with the template, to the webhook URL
+func (w *webhookHandler) Send(msg *Message) error {
+	var body []byte
+	if w.template != nil {
+		var buf bytes.Buffer
+		if err := w.template.Execute(&buf, msg); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	} else {
+		b, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	for k, v := range w.Headers {
+		headers[k] = v
+	}
+
+	if w.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.Secret))
+		mac.Write(body)
+		headers[w.SignatureHeader] = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	_, err := doRequest(w.client, w.Method, w.URL, headers, body)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Message successfully sent to webhook '%s'", w.URL)
+	return nil
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookHandlerSend(t *testing.T) {
+	tests := []struct {
+		name   string
+		config WebhookConfig
+		method string
+		body   string
+		header string
+	}{
+		{
+			name:   "json",
+			config: WebhookConfig{},
+			method: http.MethodPost,
+			body:   `"title":"disk failed"`,
+		},
+		{
+			name:   "template",
+			config: WebhookConfig{Method: "put", Template: `{"text": {{json .Title}}}`},
+			method: http.MethodPut,
+			body:   `{"text": "disk failed"}`,
+		},
+		{
+			name:   "signed",
+			config: WebhookConfig{Secret: "secret", Headers: map[string]string{"X-Team": "ops"}},
+			method: http.MethodPost,
+			header: "X-Uberwachen-Signature",
+		},
+		{
+			name:   "signed custom header",
+			config: WebhookConfig{Secret: "secret", SignatureHeader: "X-Signature"},
+			method: http.MethodPost,
+			header: "X-Signature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				req = r
+				body, _ = ioutil.ReadAll(r.Body)
+			}))
+			defer srv.Close()
+
+			tt.config.URL = srv.URL
+			h, err := NewWebhookHandler(&tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			msg := &Message{Type: MsgTypeNew, Title: "disk failed", Body: "output"}
+			if err := h.Handler.Send(msg); err != nil {
+				t.Fatal(err)
+			}
+
+			if req.Method != tt.method {
+				t.Errorf("expected method %s, got %s", tt.method, req.Method)
+			}
+			if !strings.Contains(string(body), tt.body) {
+				t.Errorf("body '%s' doesn't contain '%s'", body, tt.body)
+			}
+			for k, v := range tt.config.Headers {
+				if req.Header.Get(k) != v {
+					t.Errorf("expected header %s '%s', got '%s'", k, v, req.Header.Get(k))
+				}
+			}
+			if tt.header != "" {
+				mac := hmac.New(sha256.New, []byte(tt.config.Secret))
+				mac.Write(body)
+				expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+				if sig := req.Header.Get(tt.header); sig != expected {
+					t.Errorf("expected signature '%s', got '%s'", expected, sig)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookHandlerSendError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	h, err := NewWebhookHandler(&WebhookConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = h.Handler.Send(&Message{Type: MsgTypeNew})
+	if err == nil || !strings.Contains(err.Error(), "returned status code '503' body: 'unavailable'") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestWebhookConfigValidate(t *testing.T) {
+	tests := []struct {
+		config WebhookConfig
+		err    string
+	}{
+		{WebhookConfig{Method: "patch"}, ""},
+		{WebhookConfig{Method: "GET"}, "key 'method': unsupported method 'GET'"},
+		{WebhookConfig{Template: "{{.Title"}, "key 'template':"},
+	}
+
+	for _, tt := range tests {
+		err := tt.config.Validate()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%+v: expected error '%s', got %v", tt.config, tt.err, err)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package loaders
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Extensions are the supported definition file extensions
+var Extensions = []string{".json", ".yaml", ".yml", ".toml"}
+
+var (
+	yamlLineRe = regexp.MustCompile(`line (\d+): `)
+	tomlLineRe = regexp.MustCompile(`^\((\d+), \d+\): `)
+)
+
+// Document is a decoded definition file
+type Document struct {
+	File  string
+	Data  map[string]interface{}
+	lines map[string]int
+}
+
+// IsDefinitionFile checks if a file has one of the supported extensions
+func IsDefinitionFile(path string) bool {
+	ext := filepath.Ext(path)
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadFile reads and decodes a JSON, YAML or TOML definition file
+func ReadFile(file string) (*Document, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		File:  file,
+		lines: make(map[string]int),
+	}
+
+	switch filepath.Ext(file) {
+	case ".json":
+		err = doc.decodeJSON(b)
+	case ".yaml", ".yml":
+		err = doc.decodeYAML(b)
+	case ".toml":
+		err = doc.decodeTOML(b)
+	default:
+		err = errors.New(fmt.Sprintf("%s: unsupported file extension", file))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// Line returns the line a top-level key is defined on or 0 if it's unknown
+func (d *Document) Line(key string) int {
+	return d.lines[key]
+}
+
+// Errorf returns an error prefixed with the file and line of a top-level key
+func (d *Document) Errorf(key string, format string, a ...interface{}) error {
+	msg := fmt.Sprintf(format, a...)
+	if line := d.Line(key); line > 0 {
+		return errors.New(fmt.Sprintf("%s:%d: %s", d.File, line, msg))
+	}
+	return errors.New(fmt.Sprintf("%s: %s", d.File, msg))
+}
+
+func (d *Document) lineError(line int, msg string) error {
+	if line > 0 {
+		return errors.New(fmt.Sprintf("%s:%d: %s", d.File, line, msg))
+	}
+	return errors.New(fmt.Sprintf("%s: %s", d.File, msg))
+}
+
+func (d *Document) decodeJSON(b []byte) error {
+	lineAt := func(offset int64) int {
+		if offset > int64(len(b)) {
+			offset = int64(len(b))
+		}
+		return bytes.Count(b[:offset], []byte("\n")) + 1
+	}
+
+	if err := json.Unmarshal(b, &d.Data); err != nil {
+		switch e := err.(type) {
+		case *json.SyntaxError:
+			return d.lineError(lineAt(e.Offset), e.Error())
+		case *json.UnmarshalTypeError:
+			return d.lineError(lineAt(e.Offset), "top-level value must be an object")
+		}
+		return d.lineError(0, err.Error())
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if _, err := dec.Token(); err != nil { // opening brace
+		return d.lineError(0, err.Error())
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return d.lineError(lineAt(dec.InputOffset()), err.Error())
+		}
+
+		key, _ := t.(string)
+		d.lines[key] = lineAt(dec.InputOffset())
+
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return d.lineError(lineAt(dec.InputOffset()), err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (d *Document) decodeYAML(b []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
+		return yamlError(d, err)
+	}
+
+	if len(root.Content) == 0 {
+		d.Data = map[string]interface{}{}
+		return nil
+	}
+
+	node := root.Content[0]
+	if node.Kind != yaml.MappingNode {
+		return d.lineError(node.Line, "top-level value must be a mapping")
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		d.lines[node.Content[i].Value] = node.Content[i].Line
+	}
+
+	var m map[string]interface{}
+	if err := node.Decode(&m); err != nil {
+		return yamlError(d, err)
+	}
+
+	d.Data = normalize(m).(map[string]interface{})
+	return nil
+}
+
+func yamlError(d *Document, err error) error {
+	msg := err.Error()
+	if match := yamlLineRe.FindStringSubmatchIndex(msg); match != nil {
+		line, _ := strconv.Atoi(msg[match[2]:match[3]])
+		return d.lineError(line, msg[match[1]:])
+	}
+	return d.lineError(0, msg)
+}
+
+func (d *Document) decodeTOML(b []byte) error {
+	tree, err := toml.LoadBytes(b)
+	if err != nil {
+		msg := err.Error()
+		if match := tomlLineRe.FindStringSubmatchIndex(msg); match != nil {
+			line, _ := strconv.Atoi(msg[match[2]:match[3]])
+			return d.lineError(line, msg[match[1]:])
+		}
+		return d.lineError(0, msg)
+	}
+
+	for _, key := range tree.Keys() {
+		d.lines[key] = tree.GetPosition(key).Line
+	}
+
+	d.Data = normalize(tree.ToMap()).(map[string]interface{})
+	return nil
+}
+
+// normalize converts the values decoded from YAML and TOML to the
+// types encoding/json produces, so every format has the same semantics
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[k] = normalize(val)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprintf("%v", k)] = normalize(val)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, val := range t {
+			s[i] = normalize(val)
+		}
+		return s
+	case []map[string]interface{}:
+		s := make([]interface{}, len(t))
+		for i, val := range t {
+			s[i] = normalize(val)
+		}
+		return s
+	case int:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case uint64:
+		return float64(t)
+	default:
+		return v
+	}
+}
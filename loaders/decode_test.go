@@ -0,0 +1,116 @@
+package loaders
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestReadFile(t *testing.T) {
+	expected := map[string]interface{}{
+		"disk": map[string]interface{}{
+			"command":  "check_disk",
+			"interval": float64(60),
+			"handlers": []interface{}{"slack"},
+			"labels":   map[string]interface{}{"team": "ops"},
+		},
+		"load": map[string]interface{}{"command": "check_load"},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"checks.json", `{
+  "disk": {"command": "check_disk", "interval": 60, "handlers": ["slack"], "labels": {"team": "ops"}},
+
+  "load": {"command": "check_load"}
+}`},
+		{"checks.yaml", `disk:
+  command: check_disk
+  interval: 60
+  handlers: [slack]
+  labels:
+    team: ops
+load:
+  command: check_load
+`},
+		{"checks.toml", `[disk]
+command = "check_disk"
+interval = 60
+handlers = ["slack"]
+[disk.labels]
+team = "ops"
+[load]
+command = "check_load"
+`},
+	}
+
+	lines := map[string][2]int{"checks.json": {2, 4}, "checks.yaml": {1, 7}, "checks.toml": {1, 7}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ReadFile(writeFile(t, tt.name, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(doc.Data, expected) {
+				t.Errorf("expected %v got %v", expected, doc.Data)
+			}
+			if doc.Line("disk") != lines[tt.name][0] || doc.Line("load") != lines[tt.name][1] {
+				t.Errorf("expected lines %v, got %d %d", lines[tt.name], doc.Line("disk"), doc.Line("load"))
+			}
+			if doc.Line("nope") != 0 {
+				t.Error("unknown key has a line")
+			}
+		})
+	}
+}
+
+func TestReadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		err     string
+	}{
+		{"json syntax", "checks.json", "{\n  \"disk\": {\"command\": }\n}", "checks.json:2: invalid character"},
+		{"json array", "checks.json", `["disk"]`, "checks.json:1: top-level value must be an object"},
+		{"yaml syntax", "checks.yaml", "disk:\n  command: [\n", "checks.yaml:2: did not find expected node content"},
+		{"yaml list", "checks.yaml", "- disk\n", "checks.yaml:1: top-level value must be a mapping"},
+		{"toml syntax", "checks.toml", "[disk]\ncommand = \n", "checks.toml:3: expecting a value"},
+		{"extension", "checks.ini", "disk=1", "unsupported file extension"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadFile(writeFile(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error '%s', got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestIsDefinitionFile(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"checks.json": true, "checks.yaml": true, "checks.yml": true, "checks.toml": true,
+		"checks.json.bak": false, "README.md": false, "checks": false,
+	} {
+		if IsDefinitionFile(path) != expected {
+			t.Errorf("%s: expected %v", path, expected)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package loaders
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alexferl/uberwachen/util"
+)
+
+// errTypef returns an error for a key holding a value of the wrong type
+func errTypef(key, expected string, v interface{}) error {
+	return errors.New(fmt.Sprintf("key '%s': expected %s, got %s", key, expected, util.TypeName(v)))
+}
+
+// wrapErrors calls wrap on every error held by err
+func wrapErrors(err error, wrap func(e error) error) util.Errors {
+	var errs util.Errors
+	if multi, ok := err.(util.Errors); ok {
+		for _, e := range multi {
+			errs = append(errs, wrap(e))
+		}
+		return errs
+	}
+	return append(errs, wrap(err))
+}
@@ -3,28 +3,47 @@ package loaders
 import (
 	"encoding/json"
 	"errors"
-	"io/ioutil"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/alexferl/uberwachen/handlers"
 	"github.com/alexferl/uberwachen/registries"
+	"github.com/alexferl/uberwachen/util"
+)
+
+const (
+	// DuplicateError reports checks defined more than once as errors
+	DuplicateError = "error"
+	// DuplicateFirstWins keeps the first definition of a check
+	DuplicateFirstWins = "first-wins"
+	// DuplicateLastWins keeps the last definition of a check
+	DuplicateLastWins = "last-wins"
 )
 
 type FileLoader struct {
-	Path   string
-	Checks []*handlers.Check
+	Path            string
+	DuplicatePolicy string
+	Checks          []*handlers.Check
 }
 
-func NewFileLoader(path string) Loader {
+func NewFileLoader(path, duplicatePolicy string) Loader {
 	return Loader(&FileLoader{
-		Path: path,
+		Path:            path,
+		DuplicatePolicy: duplicatePolicy,
 	})
 }
 
+// Load adds the checks from every definition file and
+// returns all the problems found instead of stopping at the first one
 func (fl *FileLoader) Load(registry *registries.Handlers) error {
+	var errs util.Errors
+
 	abs, err := filepath.Abs(fl.Path)
 	if err != nil {
 		return err
@@ -32,6 +51,12 @@ func (fl *FileLoader) Load(registry *registries.Handlers) error {
 
 	log.Debug().Msgf("Reading in checks from '%s'", abs)
 
+	switch fl.DuplicatePolicy {
+	case DuplicateError, DuplicateFirstWins, DuplicateLastWins:
+	default:
+		return errors.New(fmt.Sprintf("unknown duplicate checks policy '%s'", fl.DuplicatePolicy))
+	}
+
 	_, pErr := fl.pathExists()
 	if pErr != nil {
 		return pErr
@@ -54,94 +79,223 @@ func (fl *FileLoader) Load(registry *registries.Handlers) error {
 
 		log.Debug().Msgf("Reading check file '%s'", abs)
 
-		f, err := ioutil.ReadFile(file)
+		doc, err := ReadFile(file)
 		if err != nil {
-			return err
-		}
-
-		var m map[string]interface{}
-		if err := json.Unmarshal(f, &m); err != nil {
-			return err
+			errs = append(errs, err)
+			continue
 		}
 
-		err = fl.parseChecks(m, registry)
-		if err != nil {
-			return err
-		}
+		errs = errs.Append(fl.parseChecks(doc, registry))
 	}
 
-	return nil
+	return errs.ErrorOrNil()
 }
 
-func checkInSlice(check *handlers.Check, slice []*handlers.Check) bool {
-	for _, c := range slice {
+func checkIndex(check *handlers.Check, slice []*handlers.Check) int {
+	for i, c := range slice {
 		if c.Name == check.Name {
+			return i
+		}
+	}
+	return -1
+}
+
+func handlerInSlice(handler *handlers.Handler, slice []*handlers.Handler) bool {
+	for _, h := range slice {
+		if h.Name == handler.Name {
 			return true
 		}
 	}
 	return false
 }
 
-func (fl *FileLoader) parseChecks(m map[string]interface{}, registry *registries.Handlers) error {
-	keys := make([]string, len(m))
+func (fl *FileLoader) parseChecks(doc *Document, registry *registries.Handlers) error {
+	var errs util.Errors
 
-	i := 0
-	for s := range m {
-		keys[i] = s
-		i++
+	keys := make([]string, 0, len(doc.Data))
+	for k := range doc.Data {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
 	for _, key := range keys {
-		if _, ok := m[key]; ok {
-			b, err := json.Marshal(m[key])
-			if err != nil {
-				return err
-			}
+		valid := true
+		checkErr := func(format string, a ...interface{}) {
+			valid = false
+			errs = append(errs, doc.Errorf(key, "check '%s': %s", key, fmt.Sprintf(format, a...)))
+		}
 
-			var cl handlers.CheckLoad
-			if err := json.Unmarshal(b, &cl); err != nil {
-				return err
-			}
+		cl, decodeErrs := decodeCheck(doc.Data[key])
+		for _, err := range decodeErrs {
+			checkErr("%v", err)
+		}
+		if cl == nil {
+			continue
+		}
 
-			c := handlers.NewCheck()
-			c.Name = key
+		c := handlers.NewCheck()
+		c.Name = key
+		c.Source = &handlers.Source{File: doc.File, Line: doc.Line(key)}
 
-			if cl.Command == "" {
-				return errors.New("command is required")
+		m := doc.Data[key].(map[string]interface{})
+
+		if cl.Command == "" {
+			if v, ok := m["command"]; !ok {
+				checkErr("key 'command' is required")
+			} else if v == "" {
+				checkErr("key 'command' cannot be empty")
 			} else {
-				c.Command = cl.Command
+				valid = false
 			}
+		} else {
+			c.Command = cl.Command
 
-			if cl.Interval == 0 {
-				return errors.New("interval is required")
-			} else {
-				c.Interval = cl.Interval
+			cmdPath := util.GetCmdPath(c.Command)
+			exists, err := util.PathExists(cmdPath)
+			if err != nil {
+				checkErr("error checking command '%s': %v", cmdPath, err)
+			} else if !exists {
+				checkErr("command '%s' does not exist", cmdPath)
 			}
+		}
 
-			if cl.MaxAttempts == 0 {
-				c.MaxAttempts = 1
+		if cl.Interval == 0 {
+			if v, ok := m["interval"]; !ok {
+				checkErr("key 'interval' is required")
+			} else if v == float64(0) {
+				checkErr("key 'interval' must be positive")
 			} else {
-				c.MaxAttempts = cl.MaxAttempts
+				valid = false
 			}
+		} else if cl.Interval < 0 {
+			checkErr("key 'interval' must be positive")
+		} else {
+			c.Interval = cl.Interval
+		}
 
-			c.Renotify = cl.Renotify
-			c.HandlerNames = cl.HandlerNames
+		if cl.MaxAttempts == 0 {
+			c.MaxAttempts = 1
+		} else {
+			c.MaxAttempts = cl.MaxAttempts
+		}
 
-			for _, handler := range cl.HandlerNames {
-				h, err := registry.Get(handler)
-				if err != nil {
-					return err
+		c.Renotify = cl.Renotify
+		c.Labels = cl.Labels
+		c.RunbookURL = cl.RunbookURL
+		c.HandlerNames = cl.HandlerNames
+
+		for _, handler := range cl.HandlerNames {
+			hs, err := registry.Resolve(handler)
+			if err != nil {
+				checkErr("%v", err)
+				continue
+			}
+			for _, h := range hs {
+				if !handlerInSlice(h, c.Handlers) {
+					c.Handlers = append(c.Handlers, h)
 				}
-				c.Handlers = append(c.Handlers, h)
 			}
+		}
 
-			if !checkInSlice(c, fl.Checks) {
-				fl.Checks = append(fl.Checks, c)
+		for _, h := range registry.Routes(c.Labels) {
+			if !handlerInSlice(h, c.Handlers) {
+				log.Debug().Msgf("Routing check '%s' to handler '%s'", c.Name, h.Name)
+				c.Handlers = append(c.Handlers, h)
 			}
+		}
+
+		if !valid {
+			continue
+		}
+
+		i := checkIndex(c, fl.Checks)
+		if i < 0 {
+			fl.Checks = append(fl.Checks, c)
+			continue
+		}
 
+		existing := fl.Checks[i].Source
+		switch fl.DuplicatePolicy {
+		case DuplicateFirstWins:
+			log.Warn().Msgf("Check '%s' defined at '%s' and '%s', keeping the first definition",
+				key, existing, c.Source)
+		case DuplicateLastWins:
+			log.Warn().Msgf("Check '%s' defined at '%s' and '%s', keeping the last definition",
+				key, existing, c.Source)
+			fl.Checks[i] = c
+		default:
+			checkErr("already defined at '%s'", existing)
 		}
 	}
-	return nil
+
+	return errs.ErrorOrNil()
+}
+
+// decodeCheck decodes a check definition key by key so every
+// unknown key and value of the wrong type gets reported
+func decodeCheck(v interface{}) (*handlers.CheckLoad, util.Errors) {
+	var errs util.Errors
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, append(errs, errors.New(fmt.Sprintf("expected object, got %s", util.TypeName(v))))
+	}
+
+	cl := &handlers.CheckLoad{}
+	rv := reflect.ValueOf(cl).Elem()
+	fields := make(map[string]reflect.Value)
+	for i := 0; i < rv.NumField(); i++ {
+		tag := strings.Split(rv.Type().Field(i).Tag.Get("json"), ",")[0]
+		if tag != "" && tag != "-" {
+			fields[tag] = rv.Field(i)
+		}
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		field, ok := fields[k]
+		if !ok {
+			errs = append(errs, errors.New(fmt.Sprintf("unknown key '%s'", k)))
+			continue
+		}
+
+		b, err := json.Marshal(m[k])
+		if err != nil {
+			errs = append(errs, errors.New(fmt.Sprintf("key '%s': %v", k, err)))
+			continue
+		}
+
+		if err := json.Unmarshal(b, field.Addr().Interface()); err != nil {
+			errs = append(errs, errTypef(k, kindName(field.Type()), m[k]))
+		}
+	}
+
+	return cl, errs
+}
+
+// kindName returns the name of a type as it's written in definition files
+func kindName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return "array of " + kindName(t.Elem())
+	case reflect.Map:
+		return "object of " + kindName(t.Elem())
+	default:
+		return t.String()
+	}
 }
 
 // isDirectory check if the path is a directory
@@ -161,11 +315,8 @@ func (fl *FileLoader) walk() ([]string, error) {
 			return dirErr
 		}
 
-		if !dir {
-			ext := filepath.Ext(path)
-			if ext == ".json" {
-				fileList = append(fileList, path)
-			}
+		if !dir && IsDefinitionFile(path) {
+			fileList = append(fileList, path)
 		}
 
 		return nil
@@ -0,0 +1,134 @@
+package loaders
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/uberwachen/handlers"
+	"github.com/alexferl/uberwachen/registries"
+)
+
+// loadChecks loads check definition files, named in the order they're read,
+// with a 'check_disk' command and a 'slack' handler available
+func loadChecks(t *testing.T, policy string, files map[string]string) (*FileLoader, error) {
+	t.Helper()
+
+	commands := t.TempDir()
+	if err := os.WriteFile(filepath.Join(commands, "check_disk"), []byte("#!/bin/sh\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("commands-path", commands)
+
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry := registries.NewHandlers()
+	if err := registry.Register(&handlers.Handler{Name: "slack"}); err != nil {
+		t.Fatal(err)
+	}
+
+	fl := NewFileLoader(dir, policy).(*FileLoader)
+	return fl, fl.Load(registry)
+}
+
+func TestFileLoaderLoad(t *testing.T) {
+	fl, err := loadChecks(t, DuplicateError, map[string]string{"checks.json": `{
+  "disk": {"command": "check_disk /", "interval": 60, "handlers": ["slack"], "labels": {"team": "ops"},
+           "runbook_url": "https://example.com/runbooks/disk"}
+}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fl.Checks) != 1 {
+		t.Fatalf("expected 1 check, got %d", len(fl.Checks))
+	}
+
+	c := fl.Checks[0]
+	if c.Name != "disk" || c.Interval != 60 || c.MaxAttempts != 1 || c.Labels["team"] != "ops" ||
+		c.RunbookURL != "https://example.com/runbooks/disk" {
+		t.Errorf("unexpected check %+v", c.CheckLoad)
+	}
+	if len(c.Handlers) != 1 || c.Handlers[0].Name != "slack" {
+		t.Errorf("unexpected handlers %v", c.Handlers)
+	}
+	if c.Source == nil || !strings.HasSuffix(c.Source.File, "checks.json") || c.Source.Line != 2 {
+		t.Errorf("unexpected source %+v", c.Source)
+	}
+}
+
+func TestFileLoaderErrors(t *testing.T) {
+	_, err := loadChecks(t, DuplicateError, map[string]string{
+		"a.json": `{
+  "missing": {"handlers": ["slack"]},
+  "empty": {"command": "", "interval": 0},
+  "types": {"command": "check_disk", "interval": "60", "nope": true},
+  "handler": {"command": "check_nope", "interval": 60, "handlers": ["nope"]}
+}`,
+		"b.json": `{"broken": `,
+	})
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+
+	expected := []string{
+		"a.json:2: check 'missing': key 'command' is required",
+		"a.json:2: check 'missing': key 'interval' is required",
+		"a.json:3: check 'empty': key 'command' cannot be empty",
+		"a.json:3: check 'empty': key 'interval' must be positive",
+		"a.json:4: check 'types': key 'interval': expected integer, got string",
+		"a.json:4: check 'types': unknown key 'nope'",
+		"a.json:5: check 'handler': command",
+		"a.json:5: check 'handler': no handler with the name 'nope' found",
+		"b.json:1:",
+	}
+	for _, e := range expected {
+		if !strings.Contains(err.Error(), e) {
+			t.Errorf("error doesn't contain '%s':\n%v", e, err)
+		}
+	}
+}
+
+func TestFileLoaderDuplicates(t *testing.T) {
+	files := map[string]string{
+		"a.json": `{"disk": {"command": "check_disk /", "interval": 60}}`,
+		"b.yaml": "disk:\n  command: check_disk /home\n  interval: 30\n",
+	}
+
+	tests := []struct {
+		policy  string
+		command string
+		err     string
+	}{
+		{DuplicateError, "", "b.yaml:1: check 'disk': already defined at"},
+		{DuplicateFirstWins, "check_disk /", ""},
+		{DuplicateLastWins, "check_disk /home", ""},
+		{"nope", "", "unknown duplicate checks policy 'nope'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			fl, err := loadChecks(t, tt.policy, files)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected error '%s', got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fl.Checks) != 1 || fl.Checks[0].Command != tt.command {
+				t.Errorf("expected command '%s', got %+v", tt.command, fl.Checks)
+			}
+		})
+	}
+}
@@ -0,0 +1,506 @@
+package loaders
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/uberwachen/factories"
+	"github.com/alexferl/uberwachen/handlers"
+	"github.com/alexferl/uberwachen/registries"
+	"github.com/alexferl/uberwachen/util"
+)
+
+type HandlerLoader struct {
+	Path string
+}
+
+func NewHandlerLoader(path string) Loader {
+	return Loader(&HandlerLoader{
+		Path: path,
+	})
+}
+
+// Load registers the handlers from every definition file and
+// returns all the problems found instead of stopping at the first one
+func (hl *HandlerLoader) Load(registry *registries.Handlers) error {
+	var errs util.Errors
+
+	abs, err := filepath.Abs(hl.Path)
+	if err != nil {
+		return err
+	}
+
+	log.Debug().Msgf("Reading in handlers from '%s'", abs)
+	files, err := hl.walk()
+	if err != nil {
+		return err
+	}
+
+	if len(files) == 0 {
+		log.Info().Msg("No handlers defined")
+	}
+
+	docs := make(map[string]*Document)
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return err
+		}
+
+		log.Debug().Msgf("Reading handler file '%s'", abs)
+		doc, err := ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		errs = errs.Append(hl.parseHandlers(doc, registry))
+		for k := range doc.Data {
+			if _, ok := docs[k]; !ok {
+				docs[k] = doc
+			}
+		}
+	}
+
+	// sets can reference handlers of any file so they're checked once all are registered
+	for _, name := range registry.Sets() {
+		if _, err := registry.Resolve(name); err != nil {
+			if doc, ok := docs[name]; ok {
+				errs = append(errs, doc.Errorf(name, "handler '%s': %v", name, err))
+			} else {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+func (hl *HandlerLoader) parseHandlers(doc *Document, registry *registries.Handlers) error {
+	var errs util.Errors
+
+	keys := make([]string, 0, len(doc.Data))
+	for k := range doc.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		handlerConfig, ok := doc.Data[k].(map[string]interface{})
+		if !ok {
+			errs = append(errs, doc.Errorf(k, "handler '%s': expected object, got %s",
+				k, util.TypeName(doc.Data[k])))
+			continue
+		}
+
+		handlerType, ok := handlerConfig["type"].(string)
+		if !ok {
+			errs = append(errs, doc.Errorf(k, "handler '%s': key 'type' is required and must be a string", k))
+			continue
+		}
+
+		log.Info().Msgf("Adding handler '%s' as type '%s'", k, handlerType)
+		newHandler, err := factories.Handler(handlerType, handlerConfig)
+		if err != nil {
+			errs = errs.Append(wrapErrors(err, func(e error) error {
+				return doc.Errorf(k, "handler '%s': %v", k, e)
+			}))
+			continue
+		}
+
+		newHandler.Name = k
+
+		if newHandler.Members() != nil {
+			err = setKeys(handlerConfig)
+			if err != nil {
+				errs = errs.Append(wrapErrors(err, func(e error) error {
+					return doc.Errorf(k, "handler '%s': %v", k, e)
+				}))
+				continue
+			}
+		}
+
+		r, err := route(handlerConfig)
+		if err != nil {
+			errs = append(errs, doc.Errorf(k, "handler '%s': %v", k, err))
+			continue
+		}
+		newHandler.Route = r
+
+		rp, err := retry(handlerConfig)
+		if err != nil {
+			errs = append(errs, doc.Errorf(k, "handler '%s': %v", k, err))
+			continue
+		}
+		newHandler.Retry = rp
+
+		ts, err := templates(handlerConfig)
+		if err != nil {
+			errs = errs.Append(wrapErrors(err, func(e error) error {
+				return doc.Errorf(k, "handler '%s': %v", k, e)
+			}))
+			continue
+		}
+		newHandler.Templates = ts
+
+		f, err := filters(handlerConfig)
+		if err != nil {
+			errs = errs.Append(wrapErrors(err, func(e error) error {
+				return doc.Errorf(k, "handler '%s': %v", k, e)
+			}))
+			continue
+		}
+		if f != nil {
+			// the types filter set by the handler type is kept when no types are given
+			if len(f.Types) == 0 && newHandler.Filter != nil {
+				f.Types = newHandler.Filter.Types
+			}
+			newHandler.Filter = f
+		}
+
+		err = registry.Register(newHandler)
+		if err != nil {
+			errs = append(errs, doc.Errorf(k, "%v", err))
+		}
+	}
+
+	return errs.ErrorOrNil()
+}
+
+// setKeys checks a set doesn't have the keys applying to the messages sent,
+// as its handlers send them with their own retry policy, templates and filters
+func setKeys(handlerConfig map[string]interface{}) error {
+	var errs util.Errors
+	for _, k := range []string{"filters", "retry", "templates"} {
+		if _, ok := handlerConfig[k]; ok {
+			errs = append(errs, errors.New(fmt.Sprintf("key '%s': not supported by sets, "+
+				"set it on the handlers of the set", k)))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// route returns the labels a handler's route matches on
+func route(handlerConfig map[string]interface{}) (handlers.Labels, error) {
+	v, ok := handlerConfig["route"]
+	if !ok {
+		return nil, nil
+	}
+
+	r, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errTypef("route", "object", v)
+	}
+
+	labels := handlers.Labels{}
+	for k, v := range r {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errTypef("route."+k, "string", v)
+		}
+		labels[k] = s
+	}
+	return labels, nil
+}
+
+// retry returns the retry policy of a handler, the missing
+// keys being taken from the default policy
+func retry(handlerConfig map[string]interface{}) (*handlers.RetryPolicy, error) {
+	v, ok := handlerConfig["retry"]
+	if !ok {
+		return nil, nil
+	}
+
+	r, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errTypef("retry", "object", v)
+	}
+
+	keys := make([]string, 0, len(r))
+	for k := range r {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	policy := *handlers.DefaultRetryPolicy
+	for _, k := range keys {
+		v := r[k]
+		switch k {
+		case "attempts":
+			n, ok := v.(float64)
+			if !ok || n < 1 || n != float64(int(n)) {
+				return nil, errors.New(fmt.Sprintf("key 'retry.attempts': expected positive integer, got %v", v))
+			}
+			policy.Attempts = int(n)
+		case "minDelay", "maxDelay":
+			s, ok := v.(string)
+			if !ok {
+				return nil, errTypef("retry."+k, "duration string", v)
+			}
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return nil, errors.New(fmt.Sprintf("key 'retry.%s': invalid duration '%s'", k, s))
+			}
+			if k == "minDelay" {
+				policy.MinDelay = d
+			} else {
+				policy.MaxDelay = d
+			}
+		case "factor":
+			f, ok := v.(float64)
+			if !ok || f < 1 {
+				return nil, errors.New(fmt.Sprintf("key 'retry.factor': expected number of at least 1, got %v", v))
+			}
+			policy.Factor = f
+		default:
+			return nil, errors.New(fmt.Sprintf("key 'retry.%s': unknown key", k))
+		}
+	}
+
+	if policy.MaxDelay < policy.MinDelay {
+		return nil, errors.New("key 'retry.maxDelay': must not be less than 'retry.minDelay'")
+	}
+
+	return &policy, nil
+}
+
+// templates returns the message templates of a handler, set for every
+// message type with 'title' and 'body' or for one with its name
+func templates(handlerConfig map[string]interface{}) (handlers.Templates, error) {
+	v, ok := handlerConfig["templates"]
+	if !ok {
+		return nil, nil
+	}
+
+	t, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errTypef("templates", "object", v)
+	}
+
+	var errs util.Errors
+	ts := handlers.Templates{}
+	all := map[string]interface{}{}
+	for k, v := range t {
+		switch k {
+		case handlers.TemplateTitle, handlers.TemplateBody:
+			all[k] = v
+		case handlers.MsgTypeNew, handlers.MsgTypeUpdate, handlers.MsgTypeResolve:
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				errs = append(errs, errTypef("templates."+k, "object", v))
+				continue
+			}
+			mt, err := messageTemplate("templates."+k, m)
+			errs = errs.Append(err)
+			ts[k] = mt
+		default:
+			errs = append(errs, errors.New(fmt.Sprintf("key 'templates.%s': unknown key", k)))
+		}
+	}
+
+	if len(all) > 0 {
+		mt, err := messageTemplate("templates", all)
+		errs = errs.Append(err)
+		ts[""] = mt
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool {
+			return errs[i].Error() < errs[j].Error()
+		})
+		return nil, errs
+	}
+
+	return ts, nil
+}
+
+// messageTemplate parses the title and body templates of a message
+func messageTemplate(prefix string, m map[string]interface{}) (*handlers.MessageTemplate, error) {
+	var errs util.Errors
+	mt := &handlers.MessageTemplate{}
+
+	for k, v := range m {
+		key := prefix + "." + k
+		if k != handlers.TemplateTitle && k != handlers.TemplateBody {
+			errs = append(errs, errors.New(fmt.Sprintf("key '%s': unknown key", key)))
+			continue
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			errs = append(errs, errTypef(key, "string", v))
+			continue
+		}
+
+		t, err := handlers.ParseTemplate(key, s)
+		if err != nil {
+			errs = append(errs, errors.New(fmt.Sprintf("key '%s': %v", key, err)))
+			continue
+		}
+
+		if k == handlers.TemplateTitle {
+			mt.Title = t
+		} else {
+			mt.Body = t
+		}
+	}
+
+	return mt, errs.ErrorOrNil()
+}
+
+// filters returns the filter selecting the messages a handler sends
+func filters(handlerConfig map[string]interface{}) (*handlers.Filter, error) {
+	v, ok := handlerConfig["filters"]
+	if !ok {
+		return nil, nil
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errTypef("filters", "object", v)
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs util.Errors
+	f := &handlers.Filter{}
+	for _, k := range keys {
+		key := "filters." + k
+		switch k {
+		case "severities":
+			values, err := stringList(key, m[k], func(s string) bool {
+				for _, status := range []int{handlers.StatusOK, handlers.StatusWarning,
+					handlers.StatusCritical, handlers.StatusUnknown} {
+					if s == handlers.StatusName(status) {
+						return true
+					}
+				}
+				return false
+			}, "'OK', 'WARNING', 'CRITICAL' or 'UNKNOWN'")
+			errs = errs.Append(err)
+			f.Severities = values
+		case "types":
+			values, err := stringList(key, m[k], func(s string) bool {
+				return s == handlers.MsgTypeNew || s == handlers.MsgTypeUpdate || s == handlers.MsgTypeResolve
+			}, fmt.Sprintf("'%s', '%s' or '%s'", handlers.MsgTypeNew, handlers.MsgTypeUpdate, handlers.MsgTypeResolve))
+			errs = errs.Append(err)
+			f.Types = values
+		case "labels":
+			labels, ok := m[k].(map[string]interface{})
+			if !ok {
+				errs = append(errs, errTypef(key, "object", m[k]))
+				continue
+			}
+			f.Labels = handlers.Labels{}
+			for name, value := range labels {
+				s, ok := value.(string)
+				if !ok {
+					errs = append(errs, errTypef(key+"."+name, "string", value))
+					continue
+				}
+				f.Labels[name] = s
+			}
+		case "window":
+			w, err := timeWindow(key, m[k])
+			errs = errs.Append(err)
+			f.Window = w
+		default:
+			errs = append(errs, errors.New(fmt.Sprintf("key '%s': unknown key", key)))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return f, nil
+}
+
+// stringList returns the strings of a list, each one being checked with valid
+func stringList(key string, v interface{}, valid func(s string) bool, expected string) ([]string, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, errTypef(key, "array", v)
+	}
+
+	var errs util.Errors
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			errs = append(errs, errTypef(key, "array of strings", v))
+			break
+		}
+		if !valid(s) {
+			errs = append(errs, errors.New(fmt.Sprintf("key '%s': unknown value '%s', must be one of %s",
+				key, s, expected)))
+			continue
+		}
+		values = append(values, s)
+	}
+
+	return values, errs.ErrorOrNil()
+}
+
+// timeWindow returns the time window of a filter
+func timeWindow(key string, v interface{}) (*handlers.TimeWindow, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errTypef(key, "object", v)
+	}
+
+	values := map[string]string{}
+	for k, v := range m {
+		if k != "start" && k != "end" && k != "timezone" {
+			return nil, errors.New(fmt.Sprintf("key '%s.%s': unknown key", key, k))
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, errTypef(key+"."+k, "string", v)
+		}
+		values[k] = s
+	}
+
+	for _, k := range []string{"start", "end"} {
+		if _, ok := values[k]; !ok {
+			return nil, errors.New(fmt.Sprintf("key '%s.%s' is required", key, k))
+		}
+	}
+
+	w, err := handlers.NewTimeWindow(values["start"], values["end"], values["timezone"])
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("key '%s': %v", key, err))
+	}
+
+	return w, nil
+}
+
+func (hl *HandlerLoader) walk() ([]string, error) {
+	var files []string
+	err := filepath.Walk(hl.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && IsDefinitionFile(path) {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
@@ -0,0 +1,145 @@
+package loaders
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alexferl/uberwachen/registries"
+)
+
+// loadHandlers loads a handlers definition file and returns the registry and the errors found
+func loadHandlers(t *testing.T, name, content string) (*registries.Handlers, error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := registries.NewHandlers()
+	return registry, NewHandlerLoader(dir).Load(registry)
+}
+
+func TestHandlerLoaderLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		errs    []string
+	}{
+		{
+			name: "valid",
+			file: "handlers.json",
+			content: `{
+  "file": {"type": "file", "path": "/tmp/uberwachen.log", "retry": {"attempts": 2},
+           "filters": {"types": ["new"], "window": {"start": "09:00", "end": "17:00"}}},
+  "team": {"type": "set", "handlers": ["file"], "route": {"team": "ops"}}
+}`,
+		},
+		{
+			name: "yaml",
+			file: "handlers.yaml",
+			content: `file:
+  type: file
+  path: /tmp/uberwachen.log
+  templates:
+    title: "{{.Check.Name}}"
+`,
+		},
+		{
+			name: "set keys",
+			file: "handlers.json",
+			content: `{
+  "file": {"type": "file", "path": "/tmp/uberwachen.log"},
+  "team": {"type": "set", "handlers": ["file"],
+           "filters": {"types": ["new"]}, "retry": {"attempts": 2}, "templates": {"title": "t"}}
+}`,
+			errs: []string{
+				"handlers.json:3: handler 'team': key 'filters': not supported by sets",
+				"handler 'team': key 'retry': not supported by sets",
+				"handler 'team': key 'templates': not supported by sets",
+			},
+		},
+		{
+			name: "set cycle",
+			file: "handlers.json",
+			content: `{
+  "a": {"type": "set", "handlers": ["b"]},
+  "b": {"type": "set", "handlers": ["a"]}
+}`,
+			errs: []string{"handler set cycle: a -> b -> a", "handler set cycle: b -> a -> b"},
+		},
+		{
+			name:    "unknown set member",
+			file:    "handlers.json",
+			content: `{"team": {"type": "set", "handlers": ["nope"]}}`,
+			errs:    []string{"no handler with the name 'nope' found in set 'team'"},
+		},
+		{
+			name: "invalid",
+			file: "handlers.json",
+			content: `{
+  "untyped": {"path": "/tmp/uberwachen.log"},
+  "unknown": {"type": "nope"},
+  "window": {"type": "file", "path": "/tmp/uberwachen.log", "filters": {"window": {"start": "09:00", "end": "09:00"}}}
+}`,
+			errs: []string{
+				"handler 'untyped': key 'type' is required",
+				"handler 'unknown': unknown handler type 'nope'",
+				"handler 'window': key 'filters.window': start and end '09:00' are equal",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadHandlers(t, tt.file, tt.content)
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %v", tt.errs)
+			}
+			for _, e := range tt.errs {
+				if !strings.Contains(err.Error(), e) {
+					t.Errorf("error '%v' doesn't contain '%s'", err, e)
+				}
+			}
+		})
+	}
+}
+
+func TestHandlerLoaderTypeFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters string
+		types   []string
+	}{
+		{"default", ``, []string{"new", "update"}},
+		{"other filters", `, "filters": {"severities": ["CRITICAL"]}`, []string{"new", "update"}},
+		{"types", `, "filters": {"types": ["new", "resolve"]}`, []string{"new", "resolve"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := loadHandlers(t, "handlers.json", `{"mail": {"type": "sendgrid", "apiKey": "key",
+  "from": "ops@example.com", "to": "a@example.com"`+tt.filters+`}}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h, err := registry.Get("mail")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.Filter == nil || strings.Join(h.Filter.Types, ",") != strings.Join(tt.types, ",") {
+				t.Errorf("expected types %v, got %+v", tt.types, h.Filter)
+			}
+		})
+	}
+}
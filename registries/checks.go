@@ -0,0 +1,75 @@
+package registries
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/uberwachen/handlers"
+)
+
+type Checks struct {
+	mu     sync.Mutex
+	checks map[string]*handlers.Check
+}
+
+func NewChecks() *Checks {
+	m := make(map[string]*handlers.Check)
+	return &Checks{
+		checks: m,
+	}
+}
+
+func (c *Checks) Register(check *handlers.Check) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	log.Debug().Msgf("Registering check '%s'", check.Name)
+
+	if _, exist := c.checks[check.Name]; !exist {
+		c.checks[check.Name] = check
+	} else {
+		return errors.New(fmt.Sprintf("check with name '%s' already registered", check.Name))
+	}
+
+	return nil
+}
+
+func (c *Checks) Get(name string) (*handlers.Check, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if val, exist := c.checks[name]; exist {
+		return val, nil
+	} else {
+		return nil, errors.New(fmt.Sprintf("no check with the name '%s' found", name))
+	}
+}
+
+// All returns every registered check sorted by name
+func (c *Checks) All() []*handlers.Check {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	checks := make([]*handlers.Check, 0, len(c.checks))
+	for _, check := range c.checks {
+		checks = append(checks, check)
+	}
+
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].Name < checks[j].Name
+	})
+
+	return checks
+}
+
+func (c *Checks) Remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	log.Debug().Msgf("Removing check '%s'", name)
+	delete(c.checks, name)
+}
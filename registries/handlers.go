@@ -3,6 +3,8 @@ package registries
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/rs/zerolog/log"
@@ -38,9 +40,139 @@ func (h *Handlers) Register(handler *handlers.Handler) error {
 }
 
 func (h *Handlers) Get(name string) (*handlers.Handler, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	if val, exist := h.handlers[name]; exist {
 		return val, nil
 	} else {
 		return nil, errors.New(fmt.Sprintf("no handler with the name '%s' found", name))
 	}
 }
+
+// Replace replaces the handlers of the registry with the ones of another registry
+func (h *Handlers) Replace(other *Handlers) {
+	other.mu.Lock()
+	hs := other.handlers
+	other.mu.Unlock()
+
+	h.mu.Lock()
+	h.handlers = hs
+	h.mu.Unlock()
+}
+
+// Resolve returns the handler with the given name, or the handlers it
+// references if it's a set, following nested sets and detecting cycles
+func (h *Handlers) Resolve(name string) ([]*handlers.Handler, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.resolve(name, nil)
+}
+
+func (h *Handlers) resolve(name string, path []string) ([]*handlers.Handler, error) {
+	for _, n := range path {
+		if n == name {
+			return nil, errors.New(fmt.Sprintf("handler set cycle: %s -> %s", strings.Join(path, " -> "), name))
+		}
+	}
+
+	handler, exist := h.handlers[name]
+	if !exist {
+		if len(path) > 0 {
+			return nil, errors.New(fmt.Sprintf("no handler with the name '%s' found in set '%s'",
+				name, path[len(path)-1]))
+		}
+		return nil, errors.New(fmt.Sprintf("no handler with the name '%s' found", name))
+	}
+
+	members := handler.Members()
+	if members == nil {
+		return []*handlers.Handler{handler}, nil
+	}
+
+	var resolved []*handlers.Handler
+	for _, member := range members {
+		hs, err := h.resolve(member, append(path, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range hs {
+			if !contains(resolved, r) {
+				resolved = append(resolved, r)
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+// Names returns the names of the handlers
+func (h *Handlers) Names() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var names []string
+	for name := range h.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Sets returns the names of the set handlers
+func (h *Handlers) Sets() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var sets []string
+	for name, handler := range h.handlers {
+		if handler.Members() != nil {
+			sets = append(sets, name)
+		}
+	}
+	sort.Strings(sets)
+
+	return sets
+}
+
+// Routes returns the handlers whose route matches the given labels,
+// sets being replaced by their handlers
+func (h *Handlers) Routes(labels handlers.Labels) []*handlers.Handler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var names []string
+	for name, handler := range h.handlers {
+		if len(handler.Route) > 0 && labels.Matches(handler.Route) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var routed []*handlers.Handler
+	for _, name := range names {
+		hs, err := h.resolve(name, nil)
+		if err != nil {
+			log.Error().Msgf("Error resolving handler '%s': %v", name, err)
+			continue
+		}
+		for _, r := range hs {
+			if !contains(routed, r) {
+				routed = append(routed, r)
+			}
+		}
+	}
+
+	return routed
+}
+
+func contains(hs []*handlers.Handler, handler *handlers.Handler) bool {
+	for _, h := range hs {
+		if h == handler {
+			return true
+		}
+	}
+	return false
+}
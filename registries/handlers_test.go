@@ -0,0 +1,163 @@
+package registries
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alexferl/uberwachen/handlers"
+)
+
+func newTestHandlers(t *testing.T, hs ...*handlers.Handler) *Handlers {
+	t.Helper()
+
+	registry := NewHandlers()
+	for _, h := range hs {
+		if err := registry.Register(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return registry
+}
+
+func handlerNames(hs []*handlers.Handler) []string {
+	names := []string{}
+	for _, h := range hs {
+		names = append(names, h.Name)
+	}
+	return names
+}
+
+func TestHandlersRoutes(t *testing.T) {
+	set := newTestSet("ops", "slack", "pagerduty")
+	set.Route = handlers.Labels{"team": "ops"}
+
+	registry := newTestHandlers(t,
+		&handlers.Handler{Name: "slack", Route: handlers.Labels{"team": "ops", "env": "prod"}},
+		&handlers.Handler{Name: "pagerduty"},
+		&handlers.Handler{Name: "dev", Route: handlers.Labels{"team": "dev"}},
+		set,
+	)
+
+	tests := []struct {
+		labels handlers.Labels
+		routed []string
+	}{
+		{nil, []string{}},
+		{handlers.Labels{"team": "dev"}, []string{"dev"}},
+		{handlers.Labels{"team": "ops"}, []string{"slack", "pagerduty"}},
+		{handlers.Labels{"team": "ops", "env": "prod"}, []string{"slack", "pagerduty"}},
+		{handlers.Labels{"team": "qa"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		routed := handlerNames(registry.Routes(tt.labels))
+		if len(routed) != len(tt.routed) {
+			t.Errorf("%v: expected %v got %v", tt.labels, tt.routed, routed)
+			continue
+		}
+		for i := range routed {
+			if routed[i] != tt.routed[i] {
+				t.Errorf("%v: expected %v got %v", tt.labels, tt.routed, routed)
+				break
+			}
+		}
+	}
+}
+
+func TestHandlersRegister(t *testing.T) {
+	registry := newTestHandlers(t, &handlers.Handler{Name: "slack"})
+
+	if err := registry.Register(&handlers.Handler{Name: "slack"}); err == nil {
+		t.Error("expected an error registering a handler twice")
+	}
+
+	if _, err := registry.Get("slack"); err != nil {
+		t.Error(err)
+	}
+	if _, err := registry.Get("nope"); err == nil {
+		t.Error("expected an error getting an unknown handler")
+	}
+}
+
+func newTestSet(name string, members ...string) *handlers.Handler {
+	set := handlers.NewSetHandler(members)
+	set.Name = name
+	return set
+}
+
+func TestHandlersResolve(t *testing.T) {
+	registry := newTestHandlers(t,
+		&handlers.Handler{Name: "slack"},
+		&handlers.Handler{Name: "pagerduty"},
+		&handlers.Handler{Name: "email"},
+		newTestSet("ops", "slack", "pagerduty"),
+		newTestSet("all", "ops", "email", "slack"),
+		newTestSet("loop", "slack", "cycle"),
+		newTestSet("cycle", "loop"),
+		newTestSet("self", "self"),
+		newTestSet("broken", "slack", "nope"),
+	)
+
+	tests := []struct {
+		name     string
+		expected []string
+		err      string
+	}{
+		{"slack", []string{"slack"}, ""},
+		{"ops", []string{"slack", "pagerduty"}, ""},
+		{"all", []string{"slack", "pagerduty", "email"}, ""},
+		{"loop", nil, "handler set cycle: loop -> cycle -> loop"},
+		{"self", nil, "handler set cycle: self -> self"},
+		{"broken", nil, "no handler with the name 'nope' found in set 'broken'"},
+		{"nope", nil, "no handler with the name 'nope' found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs, err := registry.Resolve(tt.name)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected error '%s', got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := handlerNames(hs); !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+}
+
+func TestHandlersSets(t *testing.T) {
+	registry := newTestHandlers(t,
+		&handlers.Handler{Name: "slack"},
+		newTestSet("ops", "slack"),
+		newTestSet("all", "ops"),
+	)
+
+	expected := []string{"all", "ops"}
+	if sets := registry.Sets(); !reflect.DeepEqual(sets, expected) {
+		t.Errorf("expected %v, got %v", expected, sets)
+	}
+
+	if sets := NewHandlers().Sets(); len(sets) != 0 {
+		t.Errorf("expected no sets, got %v", sets)
+	}
+}
+
+func TestHandlersNames(t *testing.T) {
+	registry := newTestHandlers(t,
+		&handlers.Handler{Name: "slack"},
+		&handlers.Handler{Name: "email"},
+		newTestSet("ops", "slack"),
+	)
+
+	expected := []string{"email", "ops", "slack"}
+	if names := registry.Names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
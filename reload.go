@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+)
+
+// reloadDelay is how long to wait for file changes to settle before reloading
+const reloadDelay = 500 * time.Millisecond
+
+// watch reloads the checks and handlers on SIGHUP or when their definition files change
+func watch(s *scheduler) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var events chan fsnotify.Event
+	var errs chan error
+	var watcher *fsnotify.Watcher
+
+	if viper.GetBool("watch-files") {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			log.Error().Msgf("Error creating file watcher: %v", err)
+		} else {
+			defer w.Close()
+			watcher = w
+			addWatches(watcher)
+			events = watcher.Events
+			errs = watcher.Errors
+		}
+	}
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-hup:
+			log.Info().Msg("Received SIGHUP")
+			reload(s)
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			if e.Op == fsnotify.Chmod {
+				continue
+			}
+			log.Debug().Msgf("Detected change to '%s'", e.Name)
+			debounce = time.After(reloadDelay)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			log.Error().Msgf("Error watching files: %v", err)
+		case <-debounce:
+			debounce = nil
+			addWatches(watcher)
+			reload(s)
+		}
+	}
+}
+
+// reload loads the checks and handlers and applies them to the scheduler.
+// The running checks and handlers are kept if loading fails.
+func reload(s *scheduler) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Msgf("Error reloading checks and handlers, keeping current configuration: %v", r)
+		}
+	}()
+
+	log.Info().Msg("Reloading checks and handlers")
+	checks, registry, err := load()
+	if err != nil {
+		logErrors("Error reloading checks and handlers, keeping current configuration", err)
+		return
+	}
+
+	s.apply(checks, registry)
+}
+
+// addWatches watches every directory under the checks and handlers paths
+func addWatches(watcher *fsnotify.Watcher) {
+	if watcher == nil {
+		return
+	}
+
+	for _, keyName := range []string{"checks-path", "handlers-path"} {
+		root := viper.GetString(keyName)
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				return watcher.Add(path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			log.Error().Msgf("Error watching %s: %v", keyName, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/uberwachen/handlers"
+	"github.com/alexferl/uberwachen/registries"
+)
+
+// scheduler runs the registered checks on their interval
+type scheduler struct {
+	mu       sync.Mutex
+	checks   *registries.Checks
+	handlers *registries.Handlers
+	stops    map[string]chan struct{}
+}
+
+func newScheduler(checks *registries.Checks, handlers *registries.Handlers) *scheduler {
+	return &scheduler{
+		checks:   checks,
+		handlers: handlers,
+		stops:    make(map[string]chan struct{}),
+	}
+}
+
+// apply diffs the given checks against the scheduled ones. New checks are
+// scheduled, removed checks are stopped and changed checks are rescheduled.
+// Unchanged checks keep their state and only get their handlers swapped.
+// The handlers of the registry are replaced with the given ones, closing the
+// queues of the removed ones.
+func (s *scheduler) apply(checks []*handlers.Check, registry *registries.Handlers) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers.Replace(registry)
+	viper.Get("dispatcher").(*handlers.Dispatcher).Prune(s.handlers.Names())
+
+	seen := make(map[string]bool)
+	for _, c := range checks {
+		seen[c.Name] = true
+
+		current, err := s.checks.Get(c.Name)
+		if err != nil {
+			s.start(c)
+			continue
+		}
+
+		if reflect.DeepEqual(current.CheckLoad, c.CheckLoad) {
+			log.Debug().Msgf("Check '%s' unchanged", c.Name)
+			current.SetHandlers(c.Handlers, c.Source)
+			continue
+		}
+
+		log.Info().Msgf("Check '%s' changed, rescheduling", c.Name)
+		s.stop(c.Name)
+		s.start(c)
+	}
+
+	for _, c := range s.checks.All() {
+		if !seen[c.Name] {
+			log.Info().Msgf("Check '%s' removed, unscheduling", c.Name)
+			s.stop(c.Name)
+		}
+	}
+}
+
+func (s *scheduler) start(c *handlers.Check) {
+	err := s.checks.Register(c)
+	if err != nil {
+		log.Error().Msgf("Error registering check '%s': %v", c.Name, err)
+		return
+	}
+
+	log.Info().Msgf("Scheduling check '%s'", c.Name)
+	stop := make(chan struct{})
+	s.stops[c.Name] = stop
+	go runEvery(time.Duration(int32(c.Interval))*time.Second, handlers.RunCheck, c, stop)
+
+	if viper.GetBool("run-checks-on-start") {
+		go handlers.RunCheck(c)
+	}
+}
+
+func (s *scheduler) stop(name string) {
+	if stop, ok := s.stops[name]; ok {
+		close(stop)
+		delete(s.stops, name)
+	}
+	s.checks.Remove(name)
+}
+
+func runEvery(d time.Duration, f func(c *handlers.Check), c *handlers.Check, stop <-chan struct{}) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f(c)
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/uberwachen/handlers"
+	"github.com/alexferl/uberwachen/registries"
+	"github.com/alexferl/uberwachen/storage"
+)
+
+// memStorage keeps nothing, the methods the scheduler doesn't use panic
+type memStorage struct {
+	storage.Storage
+}
+
+func (memStorage) Get(ctx context.Context, name string, item interface{}) error { return nil }
+func (memStorage) Set(ctx context.Context, data interface{}) error              { return nil }
+func (memStorage) SetDelivery(ctx context.Context, data interface{}) error      { return nil }
+
+type nopSender struct{}
+
+func (nopSender) Send(msg *handlers.Message) error { return nil }
+
+func newTestCheck(name string, handler *handlers.Handler) *handlers.Check {
+	c := handlers.NewCheck()
+	c.Name = name
+	c.Command = "check.sh"
+	c.Interval = 60
+	c.MaxAttempts = 1
+	c.Handlers = []*handlers.Handler{handler}
+	c.Source = &handlers.Source{File: "checks.json", Line: 1}
+	return c
+}
+
+// TestMain sets the config once, as the dispatcher workers keep reading it after the tests
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "uberwachen")
+	if err != nil {
+		panic(err)
+	}
+
+	script := "#!/bin/sh\nsleep 0.01\nexit 2\n"
+	err = ioutil.WriteFile(filepath.Join(dir, "check.sh"), []byte(script), 0755)
+	if err != nil {
+		panic(err)
+	}
+
+	viper.Set("commands-path", dir)
+	viper.Set("storage", storage.Storage(memStorage{}))
+	viper.Set("dispatcher", handlers.NewDispatcher(100, 1))
+	viper.Set("run-checks-on-start", false)
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestSchedulerApplyWhileRunning(t *testing.T) {
+	s := newScheduler(registries.NewChecks(), registries.NewHandlers())
+	first := &handlers.Handler{Name: "first", Type: "test", Handler: nopSender{}}
+	s.apply([]*handlers.Check{newTestCheck("test", first)}, registries.NewHandlers())
+	defer s.apply(nil, registries.NewHandlers())
+
+	current, err := s.checks.Get("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 5; i++ {
+			handlers.RunCheck(current)
+		}
+	}()
+
+	second := &handlers.Handler{Name: "second", Type: "test", Handler: nopSender{}}
+	for i := 0; i < 50; i++ {
+		s.apply([]*handlers.Check{newTestCheck("test", second)}, registries.NewHandlers())
+	}
+	wg.Wait()
+
+	c, err := s.checks.Get("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != current {
+		t.Error("unchanged check was rescheduled")
+	}
+	if hs := c.GetHandlers(); len(hs) != 1 || hs[0].Name != "second" {
+		t.Errorf("handlers not swapped: %v", hs)
+	}
+}
@@ -2,16 +2,15 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"io/ioutil"
+	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/alexferl/uberwachen/api"
 	"github.com/alexferl/uberwachen/registries"
 
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
 	"github.com/alexferl/uberwachen/factories"
@@ -24,25 +23,37 @@ func main() {
 	c := NewConfig()
 	c.BindFlags()
 
+	if pflag.Arg(0) == "validate" {
+		os.Exit(validate())
+	}
+
 	createFolders()
 
 	log.Info().Msg("Connecting to database")
 	loadBackend()
 
+	checksRegistry := registries.NewChecks()
+	viper.Set("checks", checksRegistry)
 	handlersRegistry := registries.NewHandlers()
-	log.Info().Msg("Registering handlers")
-	loadHandlers(handlersRegistry)
+	viper.Set("handlers-registry", handlersRegistry)
+	viper.Set("dispatcher", handlers.NewDispatcher(viper.GetInt("queue-size"), viper.GetInt("queue-workers")))
+	s := newScheduler(checksRegistry, handlersRegistry)
+
+	log.Info().Msg("Validating checks and handlers")
+	checks, registry, err := load()
+	if err != nil {
+		logErrors("Invalid configuration", err)
+		os.Exit(1)
+	}
 
-	log.Info().Msg("Adding and scheduling checks")
-	loadAndScheduleChecks(handlersRegistry)
+	log.Info().Msg("Scheduling checks")
+	s.apply(checks, registry)
 
 	log.Info().Msg("Starting HTTP API")
 	go api.Start()
 
 	log.Info().Msg("Starting scheduler")
-	for {
-		select {}
-	}
+	watch(s)
 }
 
 func createFolders() {
@@ -85,119 +96,58 @@ func loadBackend() {
 	viper.Set("storage", db)
 }
 
-func loadHandlers(registry *registries.Handlers) {
-	err := registry.Register(handlers.NewConsoleHandler())
-	if err != nil {
-		log.Error().Msgf("Error registering console handler: %v", err)
-	}
+// load registers the handlers and adds the checks from their definition files.
+// Every problem found is returned, not only the first one.
+func load() ([]*handlers.Check, *registries.Handlers, error) {
+	var errs util.Errors
 
-	err = walk(registry)
-	if err != nil {
-		log.Error().Msgf("Error registering handlers: %v", err)
-	}
-}
-
-func loadAndScheduleChecks(registry *registries.Handlers) {
-	fileLoader := loaders.NewFileLoader(viper.GetString("checks-path"))
-	err := fileLoader.Load(registry)
-	if err != nil {
-		log.Error().Msgf("Error adding checks: %v", err)
-	}
-
-	for _, c := range fileLoader.(*loaders.FileLoader).Checks {
-		cmdPath := util.GetCmdPath(c.Command)
-		exists, err := util.PathExists(cmdPath)
-		if err != nil {
-			log.Error().Msgf("Error checking if command exists: %v", err)
-		}
+	handlersRegistry := registries.NewHandlers()
+	log.Info().Msg("Registering handlers")
+	errs = errs.Append(loadHandlers(handlersRegistry))
 
-		if exists {
-			log.Info().Msgf("Scheduling check '%s'", c.Name)
-			go runEvery(time.Duration(int32(c.Interval))*time.Second, handlers.RunCheck, c)
+	log.Info().Msg("Adding checks")
+	fileLoader := loaders.NewFileLoader(viper.GetString("checks-path"), viper.GetString("duplicate-checks"))
+	errs = errs.Append(fileLoader.Load(handlersRegistry))
 
-			if viper.GetBool("run-checks-on-start") {
-				go handlers.RunCheck(c)
-			}
-		} else {
-			log.Error().Msgf("Command for check '%s' does not exists", c.Name)
-		}
-	}
+	return fileLoader.(*loaders.FileLoader).Checks, handlersRegistry, errs.ErrorOrNil()
 }
 
-func runEvery(d time.Duration, f func(c *handlers.Check), c *handlers.Check) {
-	for range time.Tick(d) {
-		f(c)
+func loadHandlers(registry *registries.Handlers) error {
+	err := registry.Register(handlers.NewConsoleHandler())
+	if err != nil {
+		return err
 	}
-}
 
-func visit(files *[]string) filepath.WalkFunc {
-	return func(path string, info os.FileInfo, err error) error {
-		if filepath.Ext(path) != ".json" {
-			return nil
-		}
+	handlerLoader := loaders.NewHandlerLoader(viper.GetString("handlers-path"))
+	return handlerLoader.Load(registry)
+}
 
-		if err != nil {
-			log.Panic().Msg(err.Error())
+// logErrors logs every error held by err
+func logErrors(msg string, err error) {
+	if errs, ok := err.(util.Errors); ok {
+		for _, e := range errs {
+			log.Error().Msgf("%s: %v", msg, e)
 		}
-
-		*files = append(*files, path)
-		return nil
+		return
 	}
+	log.Error().Msgf("%s: %v", msg, err)
 }
 
-func walk(registry *registries.Handlers) error {
-	var files []string
-
-	root := viper.GetString("handlers-path")
-	abs, err := filepath.Abs(root)
+// validate checks all the checks and handlers and prints every problem found
+func validate() int {
+	_, _, err := load()
 	if err != nil {
-		return err
-	}
-
-	log.Debug().Msgf("Reading in handlers from '%s'", abs)
-	wErr := filepath.Walk(root, visit(&files))
-	if wErr != nil {
-		return wErr
-	}
-
-	if len(files) == 0 {
-		log.Info().Msg("No handlers defined")
-	}
-
-	for _, file := range files {
-		abs, err := filepath.Abs(file)
-		if err != nil {
-			return err
-		}
-
-		log.Debug().Msgf("Reading handler file '%s'", abs)
-		f, err := ioutil.ReadFile(file)
-		if err != nil {
-			return err
-		}
-
-		m := make(map[string]interface{})
-		if err := json.Unmarshal(f, &m); err != nil {
-			return err
-		}
-
-		keys := make([]string, len(m))
-		i := 0
-		for s := range m {
-			keys[i] = s
-			i++
-		}
-
-		for _, k := range keys {
-			handlerType := m[k].(map[string]interface{})["type"].(string)
-			log.Info().Msgf("Adding handler '%s' as type '%s'", k, handlerType)
-			newHandler := factories.Handler(handlerType, m[k].(map[string]interface{}))
-			newHandler.Name = k
-			err := registry.Register(newHandler)
-			if err != nil {
-				return err
+		if errs, ok := err.(util.Errors); ok {
+			for _, e := range errs {
+				fmt.Fprintln(os.Stderr, e)
 			}
+			fmt.Fprintf(os.Stderr, "%d problem(s) found\n", len(errs))
+		} else {
+			fmt.Fprintln(os.Stderr, err)
 		}
+		return 1
 	}
-	return nil
+
+	fmt.Println("Checks and handlers are valid")
+	return 0
 }
@@ -12,7 +12,9 @@ import (
 )
 
 const (
-	IncidentsColl = "incidents"
+	IncidentsColl   = "incidents"
+	DeadLettersColl = "dead_letters"
+	DeliveriesColl  = "deliveries"
 )
 
 type MongoDBOpts struct {
@@ -24,11 +26,14 @@ type MongoDBOpts struct {
 	ConnectTimeout         time.Duration
 	ServerSelectionTimeout time.Duration
 	SocketTimeout          time.Duration
+	DeliveriesTTL          time.Duration
 }
 
 type MongoDB struct {
 	client *mongo.Client
 	c      *mongo.Collection
+	dl     *mongo.Collection
+	dv     *mongo.Collection
 	opts   *MongoDBOpts
 }
 
@@ -70,6 +75,8 @@ func (md *MongoDB) Init(ctx context.Context) (err error) {
 		b.Reset()
 
 		md.c = client.Database(md.opts.DatabaseName).Collection(IncidentsColl)
+		md.dl = client.Database(md.opts.DatabaseName).Collection(DeadLettersColl)
+		md.dv = client.Database(md.opts.DatabaseName).Collection(DeliveriesColl)
 
 		t := true
 		_, err = md.c.Indexes().CreateOne(ctx, mongo.IndexModel{
@@ -82,6 +89,27 @@ func (md *MongoDB) Init(ctx context.Context) (err error) {
 			return err
 		}
 
+		_, err = md.dv.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{"incident_id", 1}},
+		})
+		if err != nil {
+			return err
+		}
+
+		if md.opts.DeliveriesTTL > 0 {
+			ttl := int32(md.opts.DeliveriesTTL.Seconds())
+			_, err = md.dv.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys: bson.D{{"timestamp", 1}},
+				Options: &options.IndexOptions{
+					ExpireAfterSeconds: &ttl,
+				},
+			})
+			if err != nil {
+				// an index with another TTL already exists, it must be dropped to change it
+				log.Error().Msgf("Error creating TTL index of deliveries: %v", err)
+			}
+		}
+
 		return nil
 	}
 }
@@ -98,6 +126,18 @@ func (md *MongoDB) Get(ctx context.Context, name string, doc interface{}) error
 	return nil
 }
 
+func (md *MongoDB) GetByID(ctx context.Context, id string, doc interface{}) error {
+	err := md.c.FindOne(ctx, bson.M{"_id": id}).Decode(doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (md *MongoDB) GetAll(ctx context.Context, docs interface{}) error {
 	cur, err := md.c.Find(ctx, bson.M{})
 	if err != nil {
@@ -135,6 +175,16 @@ func (md *MongoDB) Update(ctx context.Context, name string, data interface{}) er
 	return nil
 }
 
+// UpdateFields sets only the given fields of an incident, keys can use the dot notation for nested fields
+func (md *MongoDB) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	_, err := md.c.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (md *MongoDB) Delete(ctx context.Context, name string) error {
 	_, err := md.c.DeleteOne(ctx, bson.M{"name": name})
 	if err != nil {
@@ -143,3 +193,72 @@ func (md *MongoDB) Delete(ctx context.Context, name string) error {
 
 	return nil
 }
+
+func (md *MongoDB) GetDeadLetter(ctx context.Context, id string, doc interface{}) error {
+	err := md.dl.FindOne(ctx, bson.M{"_id": id}).Decode(doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (md *MongoDB) GetAllDeadLetters(ctx context.Context, docs interface{}) error {
+	opts := options.Find().SetSort(bson.M{"failed_at": -1})
+	cur, err := md.dl.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+
+	err = cur.All(ctx, docs)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (md *MongoDB) SetDeadLetter(ctx context.Context, data interface{}) error {
+	_, err := md.dl.InsertOne(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (md *MongoDB) DeleteDeadLetter(ctx context.Context, id string) error {
+	_, err := md.dl.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (md *MongoDB) GetDeliveries(ctx context.Context, incidentIDs []string, docs interface{}) error {
+	opts := options.Find().SetSort(bson.M{"timestamp": 1})
+	cur, err := md.dv.Find(ctx, bson.M{"incident_id": bson.M{"$in": incidentIDs}}, opts)
+	if err != nil {
+		return err
+	}
+
+	err = cur.All(ctx, docs)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (md *MongoDB) SetDelivery(ctx context.Context, data interface{}) error {
+	_, err := md.dv.InsertOne(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -6,8 +6,16 @@ import "context"
 type Storage interface {
 	Init(ctx context.Context) error
 	Get(ctx context.Context, name string, item interface{}) error
+	GetByID(ctx context.Context, id string, item interface{}) error
 	GetAll(ctx context.Context, items interface{}) error
 	Set(ctx context.Context, data interface{}) error
 	Delete(ctx context.Context, name string) error
 	Update(ctx context.Context, name string, data interface{}) error
+	UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error
+	GetDeadLetter(ctx context.Context, id string, item interface{}) error
+	GetAllDeadLetters(ctx context.Context, items interface{}) error
+	SetDeadLetter(ctx context.Context, data interface{}) error
+	DeleteDeadLetter(ctx context.Context, id string) error
+	GetDeliveries(ctx context.Context, incidentIDs []string, items interface{}) error
+	SetDelivery(ctx context.Context, data interface{}) error
 }
@@ -0,0 +1,58 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Errors holds multiple errors so they can be reported at once
+type Errors []error
+
+// Error returns every error on its own line
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Append adds an error, flattening it if it holds multiple errors
+func (e Errors) Append(err error) Errors {
+	if err == nil {
+		return e
+	}
+
+	if errs, ok := err.(Errors); ok {
+		return append(e, errs...)
+	}
+	return append(e, err)
+}
+
+// ErrorOrNil returns nil if there are no errors
+func (e Errors) ErrorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// TypeName returns the name of a decoded value's type as it's written in definition files
+func TypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int64, uint64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}